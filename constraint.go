@@ -0,0 +1,150 @@
+package trie
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Constraint reports whether a param value is valid, it is used by the typed
+// params like ":id<int>". It should be fast and not allocate, as it runs on
+// every matching of the param. With IgnoreCase, the value is checked in the
+// case of the path, as it is passed to the handler.
+type Constraint func(value string) bool
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = map[string]Constraint{
+		"int":  isInt,
+		"uuid": isUUID,
+		"slug": isSlug,
+		"date": isDate,
+	}
+)
+
+// RegisterConstraint registers a constraint by name for the typed params, it
+// replaces the one registered with the same name. The name must be word
+// characters. Builtin constraints are:
+//
+//  int:  a decimal integer in int64, e.g. "-42"
+//  uuid: a UUID in the 8-4-4-4-12 hex form, e.g. "0d6a6ff4-92b5-4d3e-b1a7-2c2f4a0b5c9e"
+//  slug: lowercase letters and digits separated by single hyphens, e.g. "hello-world"
+//  date: a valid date in the YYYY-MM-DD form, e.g. "2024-02-29"
+//
+// Constraints should be registered before defining the patterns using them.
+//
+//  trie.RegisterConstraint("hex", func(value string) bool {
+//  	_, err := hex.DecodeString(value)
+//  	return err == nil
+//  })
+//  tr.Define("/api/blob/:sha<hex>")
+//
+func RegisterConstraint(name string, constraint Constraint) {
+	if !wordReg.MatchString(name) {
+		panic(fmt.Errorf(`invalid constraint name "%s"`, name))
+	}
+	if constraint == nil {
+		panic(fmt.Errorf(`constraint "%s" is nil`, name))
+	}
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+	constraints[name] = constraint
+}
+
+func getConstraint(name string) Constraint {
+	constraintsMu.RLock()
+	defer constraintsMu.RUnlock()
+	return constraints[name]
+}
+
+// GetConstraint returns the constraint name of the param node, e.g. "int" for
+// ":id<int>", or an empty string.
+func (n *Node) GetConstraint() string {
+	return n.constraint
+}
+
+func isInt(value string) bool {
+	s := value
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	if len(s) < 19 {
+		return true
+	}
+	// may overflow int64
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+func isUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isSlug(value string) bool {
+	if value == "" || value[0] == '-' || value[len(value)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case '0' <= c && c <= '9' || 'a' <= c && c <= 'z':
+		case c == '-' && value[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isDate(value string) bool {
+	if len(value) != 10 || value[4] != '-' || value[7] != '-' {
+		return false
+	}
+	year, ok1 := atoi(value[0:4])
+	month, ok2 := atoi(value[5:7])
+	day, ok3 := atoi(value[8:10])
+	if !ok1 || !ok2 || !ok3 || month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	days := [...]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+	if month == 2 && year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+		days = 29
+	}
+	return day <= days
+}
+
+// atoi parses the digits, it returns false if not all digits.
+func atoi(s string) (int, bool) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
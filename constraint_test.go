@@ -0,0 +1,123 @@
+package trie
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGearTrieConstraint(t *testing.T) {
+	t.Run("builtin constraints", func(t *testing.T) {
+		assert := assert.New(t)
+
+		for _, c := range []struct {
+			name  string
+			valid []string
+			not   []string
+		}{
+			{"int", []string{"0", "123", "-42", "+7", "9223372036854775807", "-9223372036854775808"},
+				[]string{"", "-", "1.5", "12a", "9223372036854775808", "0x10"}},
+			{"uuid", []string{"0d6a6ff4-92b5-4d3e-b1a7-2c2f4a0b5c9e", "0D6A6FF4-92B5-4D3E-B1A7-2C2F4A0B5C9E"},
+				[]string{"", "0d6a6ff492b54d3eb1a72c2f4a0b5c9e", "0d6a6ff4-92b5-4d3e-b1a7-2c2f4a0b5c9g", "0d6a6ff4-92b5-4d3e-b1a72-c2f4a0b5c9e"}},
+			{"slug", []string{"a", "hello-world", "v2-api-3"},
+				[]string{"", "-a", "a-", "a--b", "Hello", "a_b", "a b"}},
+			{"date", []string{"2024-01-31", "2024-02-29", "2000-02-29", "1999-12-01"},
+				[]string{"", "2023-02-29", "1900-02-29", "2024-13-01", "2024-00-10", "2024-04-31", "2024-1-01", "2024/01/01", "20x4-01-01"}},
+		} {
+			check := getConstraint(c.name)
+			for _, value := range c.valid {
+				assert.True(check(value), c.name+" "+value)
+			}
+			for _, value := range c.not {
+				assert.False(check(value), c.name+" "+value)
+			}
+		}
+	})
+
+	t.Run("define and match typed params", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node1 := tr.Define("/api/:type/:ID<int>")
+		node2 := tr.Define("/api/:type/:ID<uuid>")
+		node3 := tr.Define("/api/:type/:ID")
+		node4 := tr.Define("/posts/:date<date>/:slug<slug>+.html")
+		node5 := tr.Define(`/codes/:code<int>(^\d{3}$)`)
+		assert.Equal("int", node1.GetConstraint())
+		assert.Equal("uuid", node2.GetConstraint())
+		assert.Equal("", node3.GetConstraint())
+		EqualPtr(t, node1, tr.Define("/api/:type/:ID<int>"))
+
+		res := tr.Match("/api/task/123")
+		EqualPtr(t, node1, res.Node)
+		assert.Equal("123", res.Params["ID"])
+		EqualPtr(t, node2, tr.Match("/api/task/0d6a6ff4-92b5-4d3e-b1a7-2c2f4a0b5c9e").Node)
+		EqualPtr(t, node3, tr.Match("/api/task/abc").Node)
+
+		res = tr.Match("/posts/2024-02-29/hello-world.html")
+		EqualPtr(t, node4, res.Node)
+		assert.Equal("2024-02-29", res.Params["date"])
+		assert.Equal("hello-world", res.Params["slug"])
+		assert.Nil(tr.Match("/posts/2023-02-29/hello-world.html").Node)
+		assert.Nil(tr.Match("/posts/2024-02-29/hello_world.html").Node)
+
+		EqualPtr(t, node5, tr.Match("/codes/404").Node)
+		assert.Nil(tr.Match("/codes/4040").Node)
+
+		// with IgnoreCase, the constraint checks the segment as in the path
+		EqualPtr(t, node2, tr.Match("/API/Task/0D6A6FF4-92B5-4D3E-B1A7-2C2F4A0B5C9E").Node)
+		EqualPtr(t, node4, tr.Match("/POSTS/2024-02-29/hello-world.HTML").Node)
+		assert.Nil(tr.Match("/posts/2024-02-29/Hello-World.html").Node)
+
+		url, err := node1.URL(map[string]string{"type": "task", "ID": "123"})
+		assert.Nil(err)
+		assert.Equal("/api/task/123", url)
+		_, err = node1.URL(map[string]string{"type": "task", "ID": "abc"})
+		assert.Equal(`param "ID" not match the constraint in "/api/:type/:ID<int>"`, err.Error())
+
+		_, err = tr.DefineE("/api/:ID<unknown>")
+		assert.ErrorIs(err, ErrInvalidPattern)
+		_, err = tr.DefineE("/api/:ID<>")
+		assert.ErrorIs(err, ErrInvalidPattern)
+		_, err = tr.DefineE("/api/:path<int>*")
+		assert.ErrorIs(err, ErrInvalidPattern)
+	})
+
+	t.Run("RegisterConstraint", func(t *testing.T) {
+		assert := assert.New(t)
+
+		RegisterConstraint("upper", func(value string) bool {
+			return value != "" && strings.ToUpper(value) == value
+		})
+		tr := New(Options{})
+		node := tr.Define("/codes/:code<upper>")
+		EqualPtr(t, node, tr.Match("/codes/ABC").Node)
+		assert.Nil(tr.Match("/codes/abc").Node)
+
+		assert.Panics(func() { RegisterConstraint("a-b", isInt) })
+		assert.Panics(func() { RegisterConstraint("nil", nil) })
+	})
+
+	t.Run("Lint, Explain and Export typed params", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/api/:ID<int>")
+		tr.Define("/api/:ID")
+		assert.Equal(0, len(tr.Lint()))
+
+		tr.Define("/api/:ID<uuid>")
+		diagnostics := tr.Lint()
+		assert.Equal(1, len(diagnostics))
+		assert.Equal(LintOverlap, diagnostics[0].Kind)
+
+		trace, _ := tr.Explain("/api/abc")
+		assert.Equal(`matched "/api/:ID"`, trace.Result)
+		assert.Equal("int", trace.Steps[1].Candidates[0].Constraint)
+		assert.Equal("constraint <int> not matched", trace.Steps[1].Candidates[0].Result)
+
+		params := tr.Lookup("/api/:ID<int>").GetParams()
+		assert.Equal([]ParamDocument{{Name: "ID", Constraint: "int"}}, params)
+	})
+}
@@ -0,0 +1,251 @@
+package trie
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trace is a step by step explanation of matching a path, returned by
+// Trie.Explain.
+type Trace struct {
+	Path string `json:"path"`
+	// The path fixed by FixedPathRedirect, or an empty string if not changed.
+	FixedPath string      `json:"fixedPath,omitempty"`
+	Steps     []TraceStep `json:"steps"`
+	// The pattern and allow methods of the matched node.
+	Pattern string `json:"pattern,omitempty"`
+	Allow   string `json:"allow,omitempty"`
+	FPR     string `json:"fpr,omitempty"`
+	TSR     string `json:"tsr,omitempty"`
+	CIR     string `json:"cir,omitempty"`
+	// The final decision and the reasons of it.
+	Result string   `json:"result"`
+	Notes  []string `json:"notes,omitempty"`
+}
+
+// TraceStep is the matching of a segment of the path.
+type TraceStep struct {
+	Segment string `json:"segment"`
+	// The key used to look up the static child, it is lowercased if IgnoreCase.
+	Key string `json:"key"`
+	// The static child found by the key, or an empty string.
+	Static string `json:"static,omitempty"`
+	// The param children tried in order when no static child found.
+	Candidates []TraceCandidate `json:"candidates,omitempty"`
+	// The node matched by the segment, or an empty string.
+	Node string `json:"node,omitempty"`
+}
+
+// TraceCandidate is a param node tried to match a segment.
+type TraceCandidate struct {
+	Node       string `json:"node"`
+	Suffix     string `json:"suffix,omitempty"`
+	Regex      string `json:"regex,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Wildcard   bool   `json:"wildcard,omitempty"`
+	Matched    bool   `json:"matched"`
+	Result     string `json:"result"`
+}
+
+// Explain matches the path like Match and returns a trace of the decisions:
+// the static lookup and every param candidate tried for each segment, and why
+// a TSR or FPR was or wasn't produced. It is intended for debugging only.
+//
+//  trace, _ := trie.Explain("/api/user/abc")
+//  fmt.Println(trace)
+//
+func (t *Trie) Explain(path string) (*Trace, error) {
+	matched := new(Matched)
+	if err := t.MatchInto(path, matched); err != nil {
+		return nil, err
+	}
+
+	trace := &Trace{Path: path, Steps: make([]TraceStep, 0), FPR: matched.FPR, TSR: matched.TSR, CIR: matched.CIR}
+	fixed := path
+	if t.fpr {
+		if fixed = fixPath(path); fixed != path {
+			trace.FixedPath = fixed
+		}
+		if t.escapesWildcard(path) {
+			trace.Result = "not matched"
+			trace.Notes = append(trace.Notes, `no FPR: ".." climbs above the catch-all param`)
+			return trace, nil
+		}
+	}
+
+	start := 1
+	end := len(fixed)
+	parent := t.root
+	var stopped *TraceStep
+	atEnd := false
+	// the reason of a rejection by MatchInto other than the segments
+	rejected := ""
+	for i := 1; i <= end; i++ {
+		if i < end && fixed[i] != '/' {
+			continue
+		}
+		segment := fixed[start:i]
+		step := TraceStep{Segment: segment, Key: segment}
+		if t.escaped {
+			key, ok := unescapeSegment(segment)
+			if !ok {
+				trace.Steps = append(trace.Steps, step)
+				rejected = fmt.Sprintf(`segment "%s" can't be unescaped`, segment)
+				break
+			}
+			step.Key = key
+		}
+		value := step.Key
+		if t.ignoreCase {
+			step.Key = strings.ToLower(step.Key)
+		}
+		node := parent.getChild(step.Key)
+		if node != nil {
+			step.Static = node.getSegments()
+		} else if step.Key != "" {
+			for _, child := range parent.varyChildren {
+				candidate := TraceCandidate{Node: child.getSegments(), Suffix: child.suffix,
+					Constraint: child.constraint, Wildcard: child.wildcard}
+				if child.regex != nil {
+					candidate.Regex = child.regex.String()
+				}
+				if candidate.Result = rejectSegment(child, step.Key, value); candidate.Result == "" {
+					candidate.Matched = true
+					candidate.Result = "matched"
+					if child.wildcard {
+						candidate.Result = "matched, catch-all takes the rest of path"
+					}
+				}
+				step.Candidates = append(step.Candidates, candidate)
+				if candidate.Matched {
+					node = child
+					break
+				}
+			}
+		}
+		trace.Steps = append(trace.Steps, step)
+		if node == nil {
+			stopped = &trace.Steps[len(trace.Steps)-1]
+			atEnd = i == end
+			break
+		}
+
+		trace.Steps[len(trace.Steps)-1].Node = node.getSegments()
+		parent = node
+		if parent.wildcard && parent.name != "" {
+			value, ok := fixed[start:end], true
+			if t.escaped {
+				value, ok = unescapeSegment(value)
+			}
+			switch {
+			case !ok:
+				rejected = fmt.Sprintf(`catch-all value "%s" can't be unescaped`, fixed[start:end])
+			case strings.Contains(value, "..") && climbs(value):
+				rejected = fmt.Sprintf(`catch-all value "%s" climbs above the param by ".."`, value)
+			}
+			break
+		}
+		start = i + 1
+	}
+
+	note := func(format string, args ...interface{}) {
+		trace.Notes = append(trace.Notes, fmt.Sprintf(format, args...))
+	}
+	if trace.FixedPath != "" {
+		note(`FixedPathRedirect: "%s" is fixed to "%s"`, path, fixed)
+	}
+	if rejected != "" {
+		trace.Result = "not matched"
+		note("%s", rejected)
+		return trace, nil
+	}
+	switch {
+	case matched.Node != nil:
+		trace.Pattern = matched.Node.GetPattern()
+		trace.Allow = matched.Node.GetAllow()
+		trace.Result = fmt.Sprintf(`matched "%s"`, trace.Pattern)
+		return trace, nil
+	case matched.FPR != "":
+		trace.Result = fmt.Sprintf(`fixed path redirect to "%s"`, matched.FPR)
+	case matched.TSR != "":
+		trace.Result = fmt.Sprintf(`trailing slash redirect to "%s"`, matched.TSR)
+	case matched.CIR != "":
+		trace.Result = fmt.Sprintf(`case-insensitive redirect to "%s"`, matched.CIR)
+	default:
+		trace.Result = "not matched"
+	}
+
+	switch {
+	case stopped != nil && (stopped.Segment != "" || !atEnd):
+		note(`no node matches segment "%s" under "%s"`, stopped.Segment, parent.getSegments())
+	case stopped != nil:
+		note(`"%s/" is not defined`, parent.getSegments())
+		switch {
+		case !t.tsr:
+			note("no TSR: TrailingSlashRedirect is disabled")
+		case !parent.endpoint:
+			note(`no TSR: "%s" is not an endpoint`, parent.getSegments())
+		default:
+			note(`TSR: "%s" is an endpoint`, parent.getSegments())
+		}
+	default:
+		note(`"%s" is not an endpoint`, parent.getSegments())
+		switch {
+		case !t.tsr:
+			note("no TSR: TrailingSlashRedirect is disabled")
+		case parent.getChild("") == nil:
+			note(`no TSR: "%s/" is not defined`, parent.getSegments())
+		default:
+			note(`TSR: "%s/" is an endpoint`, parent.getSegments())
+		}
+	}
+	if !t.fpr {
+		note("no FPR: FixedPathRedirect is disabled")
+	} else if trace.FixedPath == "" {
+		note("no FPR: the path need not be fixed")
+	}
+	return trace, nil
+}
+
+// String returns the trace in text, one line for each decision.
+func (t *Trace) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "path: %s\n", t.Path)
+	for _, step := range t.Steps {
+		fmt.Fprintf(&b, "segment %q, key %q\n", step.Segment, step.Key)
+		if step.Static != "" {
+			fmt.Fprintf(&b, "  static: %s\n", step.Static)
+		}
+		for _, c := range step.Candidates {
+			fmt.Fprintf(&b, "  param: %s, %s\n", c.Node, c.Result)
+		}
+		if step.Node == "" {
+			b.WriteString("  no node matched\n")
+		}
+	}
+	for _, note := range t.Notes {
+		fmt.Fprintf(&b, "note: %s\n", note)
+	}
+	fmt.Fprintf(&b, "result: %s\n", t.Result)
+	return b.String()
+}
+
+// rejectSegment returns the reason why the param node rejects the segment, or
+// an empty string if accepted. The key is the segment lowercased by IgnoreCase,
+// the constraint checks the segment as in the path.
+func rejectSegment(n *Node, key, segment string) string {
+	if n.suffix != "" {
+		if key == n.suffix || !strings.HasSuffix(key, n.suffix) {
+			return "suffix not matched"
+		}
+		key = key[0 : len(key)-len(n.suffix)]
+		segment = segment[0 : len(segment)-len(n.suffix)]
+	}
+	if n.regex != nil && !n.regex.MatchString(key) {
+		return "regexp not matched"
+	}
+	if n.check != nil && !n.check(segment) {
+		return fmt.Sprintf("constraint <%s> not matched", n.constraint)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package trie
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGearTrieExplain(t *testing.T) {
+	t.Run("matched", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define(`/api/:type/:ID(^\d+$)+:cancel`).Handle("POST", func() {})
+		tr.Define(`/api/:type/:ID(^\d+$)`)
+		tr.Define(`/api/users`)
+
+		trace, err := tr.Explain("/API/task/123:cancel")
+		assert.Nil(err)
+		assert.Equal(`matched "/api/:type/:ID(^\d+$)+:cancel"`, trace.Result)
+		assert.Equal("POST", trace.Allow)
+		assert.Equal(3, len(trace.Steps))
+		assert.Equal("api", trace.Steps[0].Key)
+		assert.Equal("/api", trace.Steps[0].Static)
+		assert.Equal("/api/:type", trace.Steps[1].Node)
+		step := trace.Steps[2]
+		assert.Equal("123:cancel", step.Segment)
+		assert.Equal(1, len(step.Candidates))
+		assert.Equal(":cancel", step.Candidates[0].Suffix)
+		assert.Equal(`^\d+$`, step.Candidates[0].Regex)
+		assert.True(step.Candidates[0].Matched)
+
+		trace, err = tr.Explain("/api/task/abc:cancel")
+		assert.Nil(err)
+		assert.Equal("not matched", trace.Result)
+		step = trace.Steps[2]
+		assert.Equal("", step.Node)
+		assert.Equal(2, len(step.Candidates))
+		assert.Equal("regexp not matched", step.Candidates[0].Result)
+		assert.Equal("regexp not matched", step.Candidates[1].Result)
+		assert.Equal(`no node matches segment "abc:cancel" under "/api/:type"`, trace.Notes[0])
+
+		data, err := json.Marshal(trace)
+		assert.Nil(err)
+		assert.Contains(string(data), `"result":"not matched"`)
+
+		trace, _ = tr.Explain("/api/task/abc")
+		assert.Equal("suffix not matched", trace.Steps[2].Candidates[0].Result)
+
+		_, err = tr.Explain("api")
+		assert.True(errors.Is(err, ErrInvalidPath))
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/a/b")
+		tr.Define("/c/")
+		tr.Define("/files/:filepath*")
+
+		trace, _ := tr.Explain("/a/b/")
+		assert.Equal(`trailing slash redirect to "/a/b"`, trace.Result)
+		assert.Equal("/a/b", trace.TSR)
+		assert.Equal(`TSR: "/a/b" is an endpoint`, trace.Notes[1])
+
+		trace, _ = tr.Explain("/c")
+		assert.Equal(`trailing slash redirect to "/c/"`, trace.Result)
+
+		trace, _ = tr.Explain("/a//b")
+		assert.Equal(`fixed path redirect to "/a/b"`, trace.Result)
+		assert.Equal("/a/b", trace.FixedPath)
+		assert.Equal(`FixedPathRedirect: "/a//b" is fixed to "/a/b"`, trace.Notes[0])
+
+		trace, _ = tr.Explain("/a")
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`"/a" is not an endpoint`, `no TSR: "/a/" is not defined`,
+			"no FPR: the path need not be fixed"}, trace.Notes)
+
+		trace, _ = tr.Explain("/c/d/../")
+		assert.Equal(`fixed path redirect to "/c/"`, trace.Result)
+		assert.Equal("/c/", trace.FixedPath)
+
+		trace, _ = tr.Explain("/files/x/../../a/b")
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`no FPR: ".." climbs above the catch-all param`}, trace.Notes)
+
+		trace, _ = tr.Explain("/files/x/y")
+		assert.Equal(`matched "/files/:filepath*"`, trace.Result)
+		assert.Equal(2, len(trace.Steps))
+		assert.True(trace.Steps[1].Candidates[0].Wildcard)
+
+		// the rejections of catch-all values without FixedPathRedirect
+		tr = New(Options{EscapedPath: true})
+		tr.Define("/static/:filepath*")
+		tr.Define("/files/:name")
+		trace, _ = tr.Explain("/static/a/../../x")
+		assert.Nil(tr.Match("/static/a/../../x").Node)
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`catch-all value "a/../../x" climbs above the param by ".."`}, trace.Notes)
+		trace, _ = tr.Explain("/static/a/%zz")
+		assert.Nil(tr.Match("/static/a/%zz").Node)
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`catch-all value "a/%zz" can't be unescaped`}, trace.Notes)
+		trace, _ = tr.Explain("/files/%zz")
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`segment "%zz" can't be unescaped`}, trace.Notes)
+		trace, _ = tr.Explain("/static/a%2Fb")
+		assert.Equal(`matched "/static/:filepath*"`, trace.Result)
+
+		tr = New(Options{CaseInsensitiveRedirect: true})
+		tr.Define("/a/b")
+		trace, _ = tr.Explain("/A/b")
+		assert.Equal(`case-insensitive redirect to "/a/b"`, trace.Result)
+		assert.Equal("/a/b", trace.CIR)
+
+		tr = New(Options{})
+		tr.Define("/a/b")
+		trace, _ = tr.Explain("/a/b/")
+		assert.Equal("not matched", trace.Result)
+		assert.Equal([]string{`"/a/b/" is not defined`, "no TSR: TrailingSlashRedirect is disabled",
+			"no FPR: FixedPathRedirect is disabled"}, trace.Notes)
+		assert.Contains(trace.String(), "result: not matched\n")
+	})
+}
@@ -0,0 +1,125 @@
+package trie
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Document is the serialisable form of a trie, see Trie.Export and Import.
+type Document struct {
+	Options Options         `json:"options"`
+	Routes  []RouteDocument `json:"routes"`
+}
+
+// RouteDocument describes an endpoint of a trie.
+type RouteDocument struct {
+	Pattern string   `json:"pattern"`
+	Methods []string `json:"methods"`
+	// The names of handlers by method.
+	Handlers map[string]string `json:"handlers,omitempty"`
+	// The parameters of the pattern, for review only.
+	Params []ParamDocument `json:"params,omitempty"`
+}
+
+// ParamDocument describes a parameter of a pattern.
+type ParamDocument struct {
+	Name       string `json:"name"`
+	Regex      string `json:"regex,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Suffix     string `json:"suffix,omitempty"`
+	Wildcard   bool   `json:"wildcard,omitempty"`
+}
+
+// Export returns the trie as a stable JSON document, routes are sorted by
+// pattern and methods are sorted by name. The name function resolves the
+// registry name of the handler registered with the method on the node, an
+// empty name exports none. It can be nil if handlers are registered by the
+// "METHOD pattern" keys, see Import.
+//
+//  data, err := tr.Export(func(node *trie.Node, method string) string {
+//  	return names[method+" "+node.GetPattern()]
+//  })
+//
+func (t *Trie) Export(name func(node *Node, method string) string) ([]byte, error) {
+	doc := Document{Options: t.GetOptions(), Routes: make([]RouteDocument, 0)}
+	for _, node := range t.GetEndpoints() {
+		route := RouteDocument{Pattern: node.pattern, Methods: node.GetMethods(), Params: node.GetParams()}
+		sort.Strings(route.Methods)
+		if name != nil {
+			for _, method := range route.Methods {
+				if n := name(node, method); n != "" {
+					if route.Handlers == nil {
+						route.Handlers = make(map[string]string)
+					}
+					route.Handlers[method] = n
+				}
+			}
+		}
+		doc.Routes = append(doc.Routes, route)
+	}
+	sort.Slice(doc.Routes, func(i, j int) bool {
+		return doc.Routes[i].Pattern < doc.Routes[j].Pattern
+	})
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Import returns a new trie built from the JSON document exported by
+// Trie.Export. Handlers are bound by name from the registry, a method without
+// handler name is bound by the "METHOD pattern" key, e.g. "GET /api/:type".
+// It returns an error if a pattern is invalid, its params differ from the
+// document, or a handler is not in the registry.
+//
+//  tr, err := trie.Import(data, map[string]interface{}{
+//  	"users.get":       getUsers,
+//  	"POST /api/users": createUser,
+//  })
+//
+func Import(data []byte, registry map[string]interface{}) (*Trie, error) {
+	doc := new(Document)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	t := New(doc.Options)
+	for _, route := range doc.Routes {
+		node, err := t.DefineE(route.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if route.Params != nil && !reflect.DeepEqual(route.Params, node.GetParams()) {
+			return nil, fmt.Errorf(`params of "%s" differ from the pattern`, route.Pattern)
+		}
+		for _, method := range route.Methods {
+			name := route.Handlers[method]
+			if name == "" {
+				name = method + " " + route.Pattern
+			}
+			handler, ok := registry[name]
+			if !ok {
+				return nil, fmt.Errorf(`handler "%s" not found for "%s %s"`, name, method, route.Pattern)
+			}
+			if err = node.HandleE(method, handler); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// GetParams returns the parameters of the node's pattern in order.
+func (n *Node) GetParams() []ParamDocument {
+	var params []ParamDocument
+	for node := n; node != nil; node = node.parent {
+		if node.name == "" {
+			continue
+		}
+		param := ParamDocument{Name: node.name, Constraint: node.constraint, Suffix: node.suffix, Wildcard: node.wildcard}
+		if node.regex != nil {
+			param.Regex = node.regex.String()
+		}
+		params = append([]ParamDocument{param}, params...)
+	}
+	return params
+}
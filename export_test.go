@@ -0,0 +1,141 @@
+package trie
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGearTrieExport(t *testing.T) {
+	t.Run("Export and Import", func(t *testing.T) {
+		assert := assert.New(t)
+
+		getTask := func() {}
+		cancelTask := func() {}
+		getFile := func() {}
+		names := map[string]string{}
+		registry := map[string]interface{}{
+			"task.get":              getTask,
+			"task.cancel":           cancelTask,
+			"GET /files/:filepath*": getFile,
+		}
+
+		tr := New(Options{IgnoreCase: true})
+		tr.Define(`/api/:type/:ID(^\d+$)`).Handle("GET", getTask)
+		tr.Define(`/api/:type/:ID(^\d+$)+:cancel`).Handle("POST", cancelTask)
+		tr.Define("/files/:filepath*").Handle("GET", getFile)
+		names[`GET /api/:type/:ID(^\d+$)`] = "task.get"
+		names[`POST /api/:type/:ID(^\d+$)+:cancel`] = "task.cancel"
+
+		data, err := tr.Export(func(node *Node, method string) string {
+			return names[method+" "+node.GetPattern()]
+		})
+		assert.Nil(err)
+		assert.Equal(`{
+  "options": {
+    "ignoreCase": true,
+    "fixedPathRedirect": false,
+    "trailingSlashRedirect": false
+  },
+  "routes": [
+    {
+      "pattern": "/api/:type/:ID(^\\d+$)",
+      "methods": [
+        "GET"
+      ],
+      "handlers": {
+        "GET": "task.get"
+      },
+      "params": [
+        {
+          "name": "type"
+        },
+        {
+          "name": "ID",
+          "regex": "^\\d+$"
+        }
+      ]
+    },
+    {
+      "pattern": "/api/:type/:ID(^\\d+$)+:cancel",
+      "methods": [
+        "POST"
+      ],
+      "handlers": {
+        "POST": "task.cancel"
+      },
+      "params": [
+        {
+          "name": "type"
+        },
+        {
+          "name": "ID",
+          "regex": "^\\d+$",
+          "suffix": ":cancel"
+        }
+      ]
+    },
+    {
+      "pattern": "/files/:filepath*",
+      "methods": [
+        "GET"
+      ],
+      "params": [
+        {
+          "name": "filepath",
+          "wildcard": true
+        }
+      ]
+    }
+  ]
+}`, string(data))
+
+		tr2, err := Import(data, registry)
+		assert.Nil(err)
+		assert.Equal(tr.GetOptions(), tr2.GetOptions())
+		res := tr2.Match("/API/task/123:cancel")
+		assert.Equal(`/api/:type/:ID(^\d+$)+:cancel`, res.Node.GetPattern())
+		EqualPtr(t, cancelTask, res.Node.GetHandler("POST"))
+		EqualPtr(t, getFile, tr2.Match("/files/a/b").Node.GetHandler("GET"))
+
+		data2, err := tr2.Export(nil)
+		assert.Nil(err)
+		assert.NotContains(string(data2), `"handlers"`)
+
+		_, err = Import(data, map[string]interface{}{})
+		assert.Equal(`handler "task.get" not found for "GET /api/:type/:ID(^\d+$)"`, err.Error())
+
+		_, err = Import([]byte(strings.Replace(string(data), `"name": "type"`, `"name": "resource"`, 1)), registry)
+		assert.Equal(`params of "/api/:type/:ID(^\d+$)" differ from the pattern`, err.Error())
+
+		_, err = Import([]byte(`{"routes": [{"pattern": "/a//b"}]}`), registry)
+		assert.Equal(`multi-slash exist: "/a//b"`, err.Error())
+
+		_, err = Import([]byte(`{`), registry)
+		assert.NotNil(err)
+	})
+
+	t.Run("Export names the same handler by route", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func() {}
+		tr := New()
+		tr.Define("/a").Handle("GET", handler)
+		tr.Define("/a").Handle("PUT", handler)
+		tr.Define("/b").Handle("GET", handler)
+		data, err := tr.Export(func(node *Node, method string) string {
+			return method + node.GetPattern()
+		})
+		assert.Nil(err)
+
+		tr, err = Import(data, map[string]interface{}{"GET/a": handler, "PUT/a": handler, "GET/b": handler})
+		assert.Nil(err)
+		doc := new(Document)
+		assert.Nil(json.Unmarshal(data, doc))
+		assert.Equal(map[string]string{"GET": "GET/a", "PUT": "PUT/a"}, doc.Routes[0].Handlers)
+		assert.Equal(map[string]string{"GET": "GET/b"}, doc.Routes[1].Handlers)
+		assert.Equal("GET, PUT", tr.Match("/a").Node.GetAllow())
+	})
+}
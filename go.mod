@@ -6,5 +6,5 @@ require (
 	github.com/dimfeld/httptreemux v5.0.1+incompatible
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/stretchr/testify v1.7.0
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,142 @@
+package trie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LintKind is the kind of a Diagnostic reported by Trie.Lint.
+type LintKind string
+
+// Kinds of Diagnostic.
+const (
+	// A param node can never be matched because a previous sibling matches
+	// every input of it.
+	LintShadowed LintKind = "shadowed"
+	// Two param siblings may match the same input, the first one defined in
+	// the matching order wins.
+	LintOverlap LintKind = "overlap"
+	// A static node matches an input that a param sibling accepts, so the
+	// param node is unreachable for that input.
+	LintUnreachable LintKind = "unreachable"
+	// A node has handlers but is not an endpoint, so they are never matched.
+	LintOrphanHandler LintKind = "orphan-handler"
+	// A node takes precedence over a catch-all sibling, matching does not
+	// backtrack to the catch-all when the rest of the path fails.
+	LintWildcard LintKind = "wildcard"
+)
+
+// Diagnostic is a problem found by Trie.Lint.
+type Diagnostic struct {
+	Kind LintKind
+	// The segments of the node with the problem, e.g. "/a/:id(^\d+$)".
+	Segments string
+	// The segments of the related node, or an empty string.
+	Related string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Kind, d.Message)
+}
+
+// Lint walks every node of the trie and reports the routes that can shadow
+// each other depending on input, the handlers that can never be matched, and
+// the ambiguities with catch-all parameters.
+//
+//  trie.Define(`/a/:id(^\d+$)`)
+//  trie.Define(`/a/:name(^[a-z0-9]+$)`)
+//  for _, d := range trie.Lint() {
+//  	fmt.Println(d) // overlap: "/a/:id(^\d+$)" and "/a/:name(^[a-z0-9]+$)" may match the same segment ...
+//  }
+//
+func (t *Trie) Lint() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	lintNode(t.root, &diagnostics)
+	return diagnostics
+}
+
+func lintNode(n *Node, diagnostics *[]Diagnostic) {
+	report := func(kind LintKind, node, related *Node, format string, args ...interface{}) {
+		d := Diagnostic{Kind: kind, Segments: node.getSegments(), Message: fmt.Sprintf(format, args...)}
+		if related != nil {
+			d.Related = related.getSegments()
+		}
+		*diagnostics = append(*diagnostics, d)
+	}
+
+	if len(n.handlers) > 0 && !n.endpoint {
+		report(LintOrphanHandler, n, nil, `"%s" has handlers for %s but is not an endpoint`,
+			n.getSegments(), n.allow)
+	}
+
+	keys := make([]string, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var wildcard *Node
+	for i, a := range n.varyChildren {
+		if a.wildcard {
+			wildcard = a
+			continue
+		}
+		for _, b := range n.varyChildren[i+1:] {
+			if b.wildcard {
+				continue
+			}
+			// a is tried before b, they may match the same segment only if
+			// one suffix ends with the other. Suffix matching takes precedence
+			// over params without suffix by design.
+			if !strings.HasSuffix(b.suffix, a.suffix) && !strings.HasSuffix(a.suffix, b.suffix) ||
+				a.suffix != "" && b.suffix == "" {
+				continue
+			}
+			switch {
+			case a.regex == nil && a.check == nil && strings.HasSuffix(b.suffix, a.suffix):
+				report(LintShadowed, b, a, `"%s" is never matched, "%s" matches every segment of it first`,
+					b.getSegments(), a.getSegments())
+			case b.regex == nil && b.check == nil && a.suffix == b.suffix:
+				// b is the fallback of a
+			default:
+				report(LintOverlap, b, a, `"%s" and "%s" may match the same segment, "%s" wins`,
+					a.getSegments(), b.getSegments(), a.getSegments())
+			}
+		}
+
+		for _, key := range keys {
+			static := n.children[key]
+			if key != "" && !static.wildcard && acceptSegment(a, key) {
+				report(LintUnreachable, a, static, `"%s" is unreachable for segment "%s", "%s" matches it`,
+					a.getSegments(), key, static.getSegments())
+			}
+		}
+	}
+
+	if wildcard != nil {
+		siblings := make([]*Node, 0)
+		for _, key := range keys {
+			if key != "" {
+				siblings = append(siblings, n.children[key])
+			}
+		}
+		for _, child := range n.varyChildren {
+			if child != wildcard {
+				siblings = append(siblings, child)
+			}
+		}
+		for _, sibling := range siblings {
+			report(LintWildcard, sibling, wildcard, `"%s" takes precedence over catch-all "%s", paths under it never fall back to the catch-all`,
+				sibling.getSegments(), wildcard.getSegments())
+		}
+	}
+
+	for _, key := range keys {
+		lintNode(n.children[key], diagnostics)
+	}
+	for _, child := range n.varyChildren {
+		lintNode(child, diagnostics)
+	}
+}
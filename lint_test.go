@@ -0,0 +1,89 @@
+package trie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGearTrieLint(t *testing.T) {
+	t.Run("clean trie", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/api/:type/:ID(^\\d+$)")
+		tr.Define("/api/:type/:ID")
+		tr.Define("/api/:type/:ID+:cancel")
+		tr.Define("/files/:filepath*")
+		tr.Define("/::a")
+		assert.Equal(0, len(tr.Lint()))
+	})
+
+	t.Run("overlap and shadowed", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define(`/a/:id(^\d+$)`)
+		tr.Define(`/a/:id(^[a-z0-9]+$)`)
+		tr.Define(`/b/:id+el`)
+		tr.Define(`/b/:id+:del`)
+
+		ds := tr.Lint()
+		assert.Equal(2, len(ds))
+		assert.Equal(LintOverlap, ds[0].Kind)
+		assert.Equal(`/a/:id(^[a-z0-9]+$)`, ds[0].Segments)
+		assert.Equal(`/a/:id(^\d+$)`, ds[0].Related)
+		assert.Equal(`overlap: "/a/:id(^\d+$)" and "/a/:id(^[a-z0-9]+$)" may match the same segment, "/a/:id(^\d+$)" wins`, ds[0].String())
+		assert.Equal(LintShadowed, ds[1].Kind)
+		assert.Equal(`/b/:id+:del`, ds[1].Segments)
+		assert.Equal(`/b/:id+el`, ds[1].Related)
+	})
+
+	t.Run("unreachable param", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/tasks/:taskId(^\\w+$)")
+		tr.Define("/tasks/batchGet")
+		tr.Define("/tasks/123-x")
+
+		ds := tr.Lint()
+		assert.Equal(1, len(ds))
+		assert.Equal(LintUnreachable, ds[0].Kind)
+		assert.Equal("/tasks/:taskId(^\\w+$)", ds[0].Segments)
+		assert.Equal("/tasks/batchGet", ds[0].Related)
+	})
+
+	t.Run("orphan handler", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node := tr.Define("/a/b")
+		tr.Define("/a/b/c")
+		node.Handle("GET", func() {})
+		node.endpoint = false
+
+		ds := tr.Lint()
+		assert.Equal(1, len(ds))
+		assert.Equal(LintOrphanHandler, ds[0].Kind)
+		assert.Equal(`orphan-handler: "/a/b" has handlers for GET but is not an endpoint`, ds[0].String())
+	})
+
+	t.Run("wildcard ambiguity", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/a/:b/c")
+		tr.Define("/a/:w*")
+		tr.Define("/a/x")
+
+		ds := tr.Lint()
+		assert.Equal(3, len(ds))
+		assert.Equal(LintUnreachable, ds[0].Kind)
+		assert.Equal(LintWildcard, ds[1].Kind)
+		assert.Equal("/a/x", ds[1].Segments)
+		assert.Equal("/a/:w*", ds[1].Related)
+		assert.Equal(LintWildcard, ds[2].Kind)
+		assert.Equal("/a/:b", ds[2].Segments)
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/dimfeld/httptreemux"
 	"github.com/julienschmidt/httprouter"
+	"github.com/teambition/trie-mux"
 	"github.com/teambition/trie-mux/mux"
 )
 
@@ -391,6 +393,90 @@ func BenchmarkHttpTreeMux(b *testing.B) {
 	benchRoutes(b, treeMux, githubAPI)
 }
 
+func benchMatchInto(b *testing.B, opts trie.Options, paths []string) {
+	patterns := make([]string, len(githubAPI))
+	for i, route := range githubAPI {
+		patterns[i] = route.path
+	}
+	benchMatchPatterns(b, opts, patterns, paths)
+}
+
+func benchMatchPatterns(b *testing.B, opts trie.Options, patterns, paths []string) {
+	tr := trie.New(opts)
+	for _, pattern := range patterns {
+		tr.Define(pattern)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	matched := trie.AcquireMatched()
+	defer trie.ReleaseMatched(matched)
+	for i := 0; i < b.N; i++ {
+		for _, path := range paths {
+			if tr.MatchInto(path, matched); matched.Node == nil {
+				b.Fatalf("%s not matched", path)
+			}
+		}
+	}
+}
+
+func githubPaths(withParams bool) []string {
+	paths := make([]string, 0)
+	for _, route := range githubAPI {
+		if strings.Contains(route.path, ":") == withParams {
+			paths = append(paths, route.path)
+		}
+	}
+	return paths
+}
+
+func BenchmarkTrieMatchIntoStatic(b *testing.B) {
+	benchMatchInto(b, trie.Options{}, githubPaths(false))
+}
+
+func BenchmarkTrieMatchIntoParams(b *testing.B) {
+	benchMatchInto(b, trie.Options{}, githubPaths(true))
+}
+
+func BenchmarkTrieMatchIntoIgnoreCase(b *testing.B) {
+	paths := githubPaths(true)
+	for i, path := range paths {
+		paths[i] = strings.ToUpper(path)
+	}
+	benchMatchInto(b, trie.Options{IgnoreCase: true}, paths)
+}
+
+// the typed params are tried in order, the rejected ones must not allocate
+var typedPatterns = []string{
+	"/repos/:owner/:repo/issues/:number<int>",
+	"/repos/:owner/:repo/issues/:name",
+	"/users/:id<uuid>",
+	"/users/:name",
+	"/posts/:date<date>/:slug<slug>+.html",
+}
+
+var typedPaths = []string{
+	"/repos/julienschmidt/httprouter/issues/123",
+	"/repos/julienschmidt/httprouter/issues/abc",
+	"/users/0d6a6ff4-92b5-4d3e-b1a7-2c2f4a0b5c9e",
+	"/users/0d6a6ff4",
+	"/posts/2024-02-29/hello-world.html",
+}
+
+func BenchmarkTrieMatchIntoTyped(b *testing.B) {
+	benchMatchPatterns(b, trie.Options{}, typedPatterns, typedPaths)
+}
+
+func BenchmarkTrieMatchIntoTypedIgnoreCase(b *testing.B) {
+	benchMatchPatterns(b, trie.Options{IgnoreCase: true}, typedPatterns, []string{
+		"/REPOS/Julienschmidt/HttpRouter/Issues/123",
+		"/Repos/julienschmidt/httprouter/ISSUES/ABC",
+		"/USERS/0D6A6FF4-92B5-4D3E-B1A7-2C2F4A0B5C9E",
+		"/Users/0D6A6FF4",
+		"/POSTS/2024-02-29/hello-world.HTML",
+	})
+}
+
 func BenchmarkTrieMuxRequests(b *testing.B) {
 	benchRequests(b, trieMux, githubAPI)
 }
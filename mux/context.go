@@ -0,0 +1,53 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	mountKey contextKey = iota
+	routeKey
+)
+
+// routeContext is the context value of a request served by an http.Handler.
+type routeContext struct {
+	route  *Route
+	params Params
+}
+
+// withParams returns a shallow copy of the request with the route and params
+// stored in its context, and on Go 1.22+ the params set as path values.
+func withParams(req *http.Request, route *Route, params Params) *http.Request {
+	req = req.WithContext(context.WithValue(req.Context(), routeKey, &routeContext{route: route, params: params}))
+	setPathValues(req, params)
+	return req
+}
+
+// ParamsFromContext returns the params of the matched route, or nil. They are
+// stored for the handlers registered by Mux.Handler, Mux.HandlerFunc and
+// Mux.Mount, so that standard http.Handler can read them.
+//
+//  mux.HandlerFunc("GET", "/view/:view", func(w http.ResponseWriter, req *http.Request) {
+//  	view := mux.ParamsFromContext(req.Context())["view"]
+//  	// on Go 1.22+: view := req.PathValue("view")
+//  })
+//
+func ParamsFromContext(ctx context.Context) Params {
+	if rc, ok := ctx.Value(routeKey).(*routeContext); ok {
+		return rc.params
+	}
+	return nil
+}
+
+// RouteFromContext returns the matched route, or nil. Use Route.GetPattern
+// and Route.GetName to get the pattern and name of it. It is stored for the
+// handlers registered by Mux.Handler and Mux.HandlerFunc.
+func RouteFromContext(ctx context.Context) *Route {
+	if rc, ok := ctx.Value(routeKey).(*routeContext); ok {
+		return rc.route
+	}
+	return nil
+}
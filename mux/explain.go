@@ -0,0 +1,35 @@
+package mux
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ExplainHandler returns a debug handler which explains how the Mux matches
+// the path given by the "path" query parameter, see trie.Trie.Explain. It
+// responds the trace in JSON, or in text with "format=text". It should not be
+// exposed publicly.
+//
+//  router.Handler("GET", "/debug/routes", router.ExplainHandler())
+//  // GET /debug/routes?path=/api/user/123&format=text
+//
+func (m *Mux) ExplainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		trace, err := m.load().trie.Explain(query.Get("path"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if query.Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(trace.String()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(trace)
+	})
+}
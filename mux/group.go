@@ -0,0 +1,99 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes on a Mux with a shared pattern prefix and shared
+// middleware. The prefix can contain parameters, their names must not conflict
+// with the patterns already defined.
+type Group struct {
+	mux        *Mux
+	prefix     string
+	middleware []Middleware
+}
+
+// Group returns a Group to register routes with the prefix and middleware.
+//
+//  repo := mux.Group("/api/v1/repos/:owner/:repo", auth)
+//  repo.Get("/issues", listIssues)             // GET /api/v1/repos/:owner/:repo/issues
+//  repo.Group("/pulls").Get("/:ID", getPull)   // GET /api/v1/repos/:owner/:repo/pulls/:ID
+//
+func (m *Mux) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{mux: m, prefix: strings.TrimSuffix(prefix, "/"), middleware: mw}
+}
+
+// Group returns a nested Group, which inherits the prefix and middleware of g.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{mux: g.mux, prefix: g.prefix + strings.TrimSuffix(prefix, "/"), middleware: g.join(mw)}
+}
+
+// Use appends middleware to the Group. They wrap the routes registered after.
+func (g *Group) Use(mw ...Middleware) {
+	g.middleware = g.join(mw)
+}
+
+// Get registers a new GET route for a path with matching handler in the Group.
+func (g *Group) Get(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodGet, pattern, handler, mw...)
+}
+
+// Head registers a new HEAD route for a path with matching handler in the Group.
+func (g *Group) Head(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodHead, pattern, handler, mw...)
+}
+
+// Post registers a new POST route for a path with matching handler in the Group.
+func (g *Group) Post(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPost, pattern, handler, mw...)
+}
+
+// Put registers a new PUT route for a path with matching handler in the Group.
+func (g *Group) Put(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPut, pattern, handler, mw...)
+}
+
+// Patch registers a new PATCH route for a path with matching handler in the Group.
+func (g *Group) Patch(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodPatch, pattern, handler, mw...)
+}
+
+// Delete registers a new DELETE route for a path with matching handler in the Group.
+func (g *Group) Delete(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodDelete, pattern, handler, mw...)
+}
+
+// Options registers a new OPTIONS route for a path with matching handler in the Group.
+func (g *Group) Options(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.Handle(http.MethodOptions, pattern, handler, mw...)
+}
+
+// Handle registers a new handler with method and the prefixed pattern in the
+// Mux, see Mux.Handle.
+func (g *Group) Handle(method, pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return g.mux.Handle(method, g.prefix+pattern, handler, g.join(mw)...)
+}
+
+// HandleE is like Handle but returns an error instead of panicking, see Mux.HandleE.
+func (g *Group) HandleE(method, pattern string, handler HandlerFunc, mw ...Middleware) (*Route, error) {
+	return g.mux.HandleE(method, g.prefix+pattern, handler, g.join(mw)...)
+}
+
+// Handler is an adapter which allows the usage of an http.Handler as a
+// request handle.
+func (g *Group) Handler(method, path string, handler http.Handler, mw ...Middleware) *Route {
+	return g.mux.Handler(method, g.prefix+path, handler, g.join(mw)...)
+}
+
+// HandlerFunc is an adapter which allows the usage of an http.HandlerFunc as a
+// request handle.
+func (g *Group) HandlerFunc(method, path string, handler http.HandlerFunc, mw ...Middleware) *Route {
+	return g.Handler(method, path, handler, mw...)
+}
+
+// join returns the middleware of the Group followed by mw.
+func (g *Group) join(mw []Middleware) []Middleware {
+	middleware := make([]Middleware, 0, len(g.middleware)+len(mw))
+	return append(append(middleware, g.middleware...), mw...)
+}
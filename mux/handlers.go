@@ -0,0 +1,152 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MethodNotAllowedFunc is a handler for the requests with a method not
+// allowed on the matched path, allow is the value of the Allow header,
+// e.g. "GET, POST".
+type MethodNotAllowedFunc func(w http.ResponseWriter, req *http.Request, allow string)
+
+// RedirectFunc is a handler for fixed path and trailing slash redirects, url
+// and code are the redirect location and status code decided by the Mux.
+type RedirectFunc func(w http.ResponseWriter, req *http.Request, url string, code int)
+
+// NotFound registers a handler in the Mux that will run if no route matches
+// the path. It takes precedence over the Otherwise handler.
+//
+//  mux.NotFound(func(w http.ResponseWriter, req *http.Request, _ mux.Params) {
+//  	w.Header().Set("Content-Type", "application/json")
+//  	w.WriteHeader(404)
+//  	w.Write([]byte(`{"error":"NotFound"}`))
+//  })
+//
+func (m *Mux) NotFound(handler HandlerFunc) {
+	m.configure()
+	m.notFound = handler
+	m.compose()
+}
+
+// MethodNotAllowed registers a handler in the Mux that will run if a route
+// matches the path but no handler is registered for the method. The Allow
+// header is set before it runs. It takes precedence over the Otherwise handler.
+//
+//  mux.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request, allow string) {
+//  	w.Header().Set("Content-Type", "application/json")
+//  	w.WriteHeader(405)
+//  	fmt.Fprintf(w, `{"error":"MethodNotAllowed","allow":"%s"}`, allow)
+//  })
+//
+func (m *Mux) MethodNotAllowed(handler MethodNotAllowedFunc) {
+	m.configure()
+	m.methodNotAllowed = handler
+	m.compose()
+}
+
+// NotFoundStatus sets the status code responded by default if no route
+// matches the path, 501 Not Implemented (default) or 404 Not Found. It panics
+// on other codes.
+func (m *Mux) NotFoundStatus(code int) {
+	m.configure()
+	if code != http.StatusNotFound && code != http.StatusNotImplemented {
+		panic(fmt.Errorf("not found status must be 404 or 501, got %d", code))
+	}
+	m.notFoundStatus = code
+	m.compose()
+}
+
+// Redirect registers a handler in the Mux to make the fixed path and trailing
+// slash redirects, instead of http.Redirect.
+func (m *Mux) Redirect(handler RedirectFunc) {
+	m.configure()
+	m.redirect = handler
+}
+
+// configure panics if the settings are of the Mux passed to the fn of
+// Mux.Update, only the routes can be changed there.
+func (s *settings) configure() {
+	if s.updating {
+		panic(errors.New("settings can't be changed in Mux.Update"))
+	}
+}
+
+// handlesNotFound reports whether a handler is registered for the paths not
+// matched, otherwise a mounted Mux leaves them to the outer Mux.
+func (s *settings) handlesNotFound() bool {
+	return s.notFound != nil || s.otherwise != nil
+}
+
+// handlesMethodNotAllowed reports whether a handler is registered for the
+// methods not allowed, otherwise a mounted Mux leaves them to the outer Mux.
+func (s *settings) handlesMethodNotAllowed() bool {
+	return s.methodNotAllowed != nil || s.otherwise != nil
+}
+
+// notFoundHandler returns the handler for the paths not matched.
+func (s *settings) notFoundHandler() HandlerFunc {
+	switch {
+	case s.notFound != nil:
+		return s.notFound
+	case s.otherwise != nil:
+		return s.otherwise
+	case s.notFoundStatus == 404:
+		return respondNotFound
+	}
+	return respondNotImplemented
+}
+
+// methodNotAllowedHandler returns the handler for the methods not allowed.
+func (s *settings) methodNotAllowedHandler() HandlerFunc {
+	switch {
+	case s.methodNotAllowed != nil:
+		handler := s.methodNotAllowed
+		return func(w http.ResponseWriter, req *http.Request, _ Params) {
+			handler(w, req, w.Header().Get("Allow"))
+		}
+	case s.otherwise != nil:
+		return s.otherwise
+	}
+	return respondMethodNotAllowed
+}
+
+func (s *settings) redirectHandler() RedirectFunc {
+	if s.redirect != nil {
+		return s.redirect
+	}
+	return http.Redirect
+}
+
+// respondOptions responds to an OPTIONS request with the Allow header set.
+func respondOptions(w http.ResponseWriter, req *http.Request, _ Params) {
+	w.WriteHeader(204)
+}
+
+// respondMethodNotAllowed responds a 405 error with the Allow header set.
+func respondMethodNotAllowed(w http.ResponseWriter, req *http.Request, _ Params) {
+	http.Error(w, fmt.Sprintf(`"%s" not allowed in "%s"`, req.Method, req.URL.Path), 405)
+}
+
+// respondNotImplemented responds a 501 error for the path not matched.
+func respondNotImplemented(w http.ResponseWriter, req *http.Request, _ Params) {
+	http.Error(w, fmt.Sprintf(`"%s" not implemented`, req.URL.Path), 501)
+}
+
+// respondNotFound responds a 404 error for the path not matched.
+func respondNotFound(w http.ResponseWriter, req *http.Request, _ Params) {
+	http.Error(w, fmt.Sprintf(`"%s" not found`, req.URL.Path), 404)
+}
+
+// respondNotAcceptable responds a 406 error for the route matched by path but
+// not by the Accept matchers.
+func respondNotAcceptable(w http.ResponseWriter, req *http.Request, _ Params) {
+	http.Error(w, fmt.Sprintf(`"%s" not acceptable`, req.Header.Get("Accept")), 406)
+}
+
+// respondUnsupportedMediaType responds a 415 error for the route matched by
+// path but not by the ContentType matchers.
+func respondUnsupportedMediaType(w http.ResponseWriter, req *http.Request, _ Params) {
+	http.Error(w, fmt.Sprintf(`"%s" not supported`, req.Header.Get("Content-Type")), 415)
+}
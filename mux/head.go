@@ -0,0 +1,71 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/teambition/trie-mux"
+)
+
+// AutoHead enables or disables HEAD requests to fall back to the GET handler
+// of the matched route when no HEAD handler is registered. The response body
+// is discarded, headers and Content-Length are preserved. HEAD is included in
+// the Allow header of the routes with a GET handler.
+func (m *Mux) AutoHead(enabled bool) {
+	m.configure()
+	m.autoHead = enabled
+}
+
+// getAllow returns the Allow header value of the node.
+func (s *settings) getAllow(node *trie.Node) string {
+	allow := node.GetAllow()
+	if !s.autoHead || node.GetHandler(http.MethodHead) != nil || node.GetHandler(http.MethodGet) == nil {
+		return allow
+	}
+	// "GET, PUT" -> "GET, HEAD, PUT"
+	i := strings.Index(", "+allow+", ", ", GET, ") + len("GET")
+	return allow[:i] + ", HEAD" + allow[i:]
+}
+
+// headWriter discards the response body and delays the header until the
+// handler returns, so that the Content-Length can be set.
+type headWriter struct {
+	http.ResponseWriter
+	code int
+	size int
+	sent bool
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(200)
+	w.size += len(b)
+	return len(b), nil
+}
+
+// Flush sends the header, the Content-Length is unknown after flushing.
+func (w *headWriter) Flush() {
+	w.send(false)
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// send sends the header if not sent.
+func (w *headWriter) send(done bool) {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	w.WriteHeader(200)
+	if done && w.size > 0 && w.code != 204 && w.code != 304 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
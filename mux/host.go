@@ -0,0 +1,85 @@
+package mux
+
+import (
+	"net"
+	"strings"
+
+	"github.com/teambition/trie-mux"
+)
+
+// hostKey is the method key of the host Mux on the nodes of a host trie.
+const hostKey = "*"
+
+// Host returns the Mux to register routes for the requests on the hosts
+// matching the pattern. The labels of the pattern are matched right-to-left
+// with the segment syntax of trie, "*" is a shorthand for ":subdomain*" and
+// matches one or more labels. Host params are merged into the path params.
+// The requests on a host not matched are routed by the Mux itself, as the
+// default host.
+//
+//  tenant := router.Host(":tenant.example.com")
+//  tenant.Get("/api/user", func(w http.ResponseWriter, req *http.Request, params mux.Params) {
+//  	// params["tenant"] == "acme" for "acme.example.com/api/user"
+//  })
+//  router.Host("*.static.example.com").Get("/:file", handler) // params["subdomain"] == "a.b" for "a.b.static.example.com"
+//
+// Like the routes, hosts should be registered before serving, or in Mux.Update.
+// The Mux returned is replaced when the routing table is updated. It shares the
+// settings with the Mux, such as NotFound, Use and RedirectPolicy, configuring
+// either configures both, before or after calling Host.
+func (m *Mux) Host(pattern string) *Mux {
+	t := m.load()
+	if t.hosts == nil {
+		t.hosts = trie.New(trie.Options{IgnoreCase: true})
+	}
+	node := t.hosts.Define(hostPattern(pattern))
+	if h, ok := node.GetHandler(hostKey).(*Mux); ok {
+		return h
+	}
+	h := &Mux{settings: m.settings}
+	h.table.Store(newTable(trie.New(t.trie.GetOptions())))
+	node.Handle(hostKey, h)
+	return h
+}
+
+// hostPattern reverses the labels of a host pattern to a trie pattern,
+// e.g. "*.example.com" -> "/com/example/:subdomain*".
+func hostPattern(pattern string) string {
+	labels := strings.Split(strings.TrimSuffix(pattern, "."), ".")
+	var b strings.Builder
+	for i := len(labels) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		if labels[i] == "*" {
+			b.WriteString(":subdomain*")
+		} else {
+			b.WriteString(labels[i])
+		}
+	}
+	return b.String()
+}
+
+// hostPath reverses the labels of a host to a trie path, the port is removed,
+// e.g. "a.b.example.com:8080" -> "/com/example/b/a".
+func hostPath(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return hostPattern(host)
+}
+
+// hostParams returns the params of a host, the labels matched by a catch-all
+// param are reversed back, e.g. "b/a" -> "a.b".
+func hostParams(values trie.Params) trie.Params {
+	params := make(trie.Params, len(values))
+	for i, param := range values {
+		if strings.IndexByte(param.Value, '/') > -1 {
+			labels := strings.Split(param.Value, "/")
+			for l, r := 0, len(labels)-1; l < r; l, r = l+1, r-1 {
+				labels[l], labels[r] = labels[r], labels[l]
+			}
+			param.Value = strings.Join(labels, ".")
+		}
+		params[i] = param
+	}
+	return params
+}
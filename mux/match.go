@@ -0,0 +1,233 @@
+package mux
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/teambition/trie-mux"
+)
+
+// Kinds of matcher, a request rejected by a matcher is responded by the
+// status of its kind if no route variant matches.
+const (
+	matchRequest     = iota // not found
+	matchAccept             // 406 Not Acceptable
+	matchContentType        // 415 Unsupported Media Type
+)
+
+type matcher struct {
+	kind  int
+	match func(req *http.Request) bool
+}
+
+// variant is a handler with matchers, registered with a method on a pattern.
+type variant struct {
+	handler  HandlerFunc
+	matchers []matcher
+}
+
+// variants are the handlers registered with the same method on a pattern,
+// tried in registration order. It is the trie handler of routes with matchers.
+type variants []*variant
+
+// Header adds a matcher to the route, the request must have the header with
+// the value, or any value if value is empty. Routes with matchers can be
+// registered with the same method on the same pattern, they are tried in
+// registration order.
+//
+//  mux.Get("/api/user", getUserV2).Header("Accept-Version", "2")
+//  mux.Get("/api/user", getUser)
+//
+func (r *Route) Header(key, value string) *Route {
+	return r.match(matchRequest, func(req *http.Request) bool {
+		if value == "" {
+			return req.Header.Get(key) != ""
+		}
+		for _, v := range req.Header.Values(key) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Query adds a matcher to the route, the request must have the query
+// parameter with the value, or any value if value is empty.
+func (r *Route) Query(key, value string) *Route {
+	return r.match(matchRequest, func(req *http.Request) bool {
+		values, ok := req.URL.Query()[key]
+		if !ok || value == "" {
+			return ok
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ContentType adds a matcher to the route, the media type of the request body
+// must be one of the types. If no route matches, it is responded with 415.
+//
+//  mux.Post("/api/user", createUser).ContentType("application/json")
+//
+func (r *Route) ContentType(types ...string) *Route {
+	return r.match(matchContentType, func(req *http.Request) bool {
+		mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+		for _, t := range types {
+			if strings.EqualFold(t, mediaType) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Accept adds a matcher to the route, the request must accept one of the
+// media types by the Accept header, or have no Accept header. If no route
+// matches, it is responded with 406.
+func (r *Route) Accept(types ...string) *Route {
+	return r.match(matchAccept, func(req *http.Request) bool {
+		accept := req.Header.Values("Accept")
+		if len(accept) == 0 {
+			return true
+		}
+		for _, value := range accept {
+			for _, part := range strings.Split(value, ",") {
+				mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+				if err != nil || params["q"] == "0" {
+					continue
+				}
+				for _, t := range types {
+					if acceptMediaType(mediaType, t) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	})
+}
+
+// MatchFunc adds a matcher to the route, the request must be accepted by fn.
+func (r *Route) MatchFunc(fn func(req *http.Request) bool) *Route {
+	return r.match(matchRequest, fn)
+}
+
+// match adds a matcher to the route, the trie handler of the route is turned
+// into variants on the first matcher.
+func (r *Route) match(kind int, fn func(req *http.Request) bool) *Route {
+	if _, ok := r.node.GetHandler(r.method).(variants); !ok {
+		r.node.Replace(r.method, variants{r.variant})
+	}
+	r.variant.matchers = append(r.variant.matchers, matcher{kind, fn})
+	return r
+}
+
+// handle registers the handler on the node as a variant if all handlers
+// registered with the method have matchers.
+func handle(node *trie.Node, method string, handler HandlerFunc) (*variant, error) {
+	v := &variant{handler: handler}
+	if vs, ok := node.GetHandler(method).(variants); ok && vs.hasMatchers() {
+		node.Replace(method, append(vs[:len(vs):len(vs)], v))
+		return v, nil
+	}
+	if err := node.HandleE(method, handler); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (vs variants) hasMatchers() bool {
+	for _, v := range vs {
+		if len(v.matchers) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a copy of the variants, and records the copies of variant.
+func (vs variants) clone(copies map[*variant]*variant) variants {
+	c := make(variants, len(vs))
+	for i, v := range vs {
+		c[i] = &variant{handler: v.handler, matchers: append([]matcher{}, v.matchers...)}
+		copies[v] = c[i]
+	}
+	return c
+}
+
+// match returns the handler of the first variant matching the request, or the
+// kind of matcher to respond with if no variant matches.
+func (vs variants) match(req *http.Request) (HandlerFunc, int) {
+	kind := matchRequest
+	for _, v := range vs {
+		k, ok := v.match(req)
+		if ok {
+			return v.handler, 0
+		}
+		if k > kind {
+			kind = k
+		}
+	}
+	return nil, kind
+}
+
+// match reports whether the request is accepted by all matchers. If not, the
+// kind is matchContentType or matchAccept if only matchers of it rejected.
+func (v *variant) match(req *http.Request) (int, bool) {
+	kind := -1
+	for _, m := range v.matchers {
+		if m.match(req) {
+			continue
+		}
+		switch {
+		case m.kind == matchRequest:
+			return matchRequest, false
+		case kind == -1 || m.kind > kind:
+			kind = m.kind
+		}
+	}
+	return kind, kind == -1
+}
+
+// getHandler returns the handler registered with the method on the node, and
+// false if not registered. For routes with matchers, it returns the handler of
+// the variant matching the request, or the handler to respond with 415, 406 or
+// not found.
+func (m *Mux) getHandler(node *trie.Node, method string, req *http.Request) (HandlerFunc, bool) {
+	switch handler := node.GetHandler(method).(type) {
+	case HandlerFunc:
+		return handler, true
+	case variants:
+		h, kind := handler.match(req)
+		switch {
+		case h != nil:
+			return h, true
+		case kind == matchContentType:
+			return m.composed.unsupportedMediaType, true
+		case kind == matchAccept:
+			return m.composed.notAcceptable, true
+		}
+		return m.composed.notFound, true
+	}
+	return nil, false
+}
+
+// acceptMediaType reports whether the media type of Accept accepts t.
+func acceptMediaType(accept, t string) bool {
+	switch {
+	case accept == "*/*" || strings.EqualFold(accept, t):
+		return true
+	case strings.HasSuffix(accept, "/*"):
+		return strings.HasPrefix(strings.ToLower(t), strings.ToLower(accept[:len(accept)-1]))
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package mux
+
+// Middleware wraps a HandlerFunc to run code before and after it, e.g. for
+// logging or authentication.
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Use appends middleware to the Mux. They wrap the routes registered after,
+// the Otherwise, NotFound and MethodNotAllowed handlers and the automatic
+// OPTIONS, 405 and 501 responses.
+// Middleware are composed once when a route is registered, not per request,
+// so they should be used before registering routes.
+//
+//  mux.Use(logger, recovery)
+//  mux.Get("/api/task/:ID", handler, auth) // logger(recovery(auth(handler)))
+//
+func (m *Mux) Use(mw ...Middleware) {
+	m.configure()
+	middleware := make([]Middleware, 0, len(m.middleware)+len(mw))
+	m.middleware = append(append(middleware, m.middleware...), mw...)
+	m.compose()
+}
+
+// compose composes the handlers of the settings with the middleware.
+func (s *settings) compose() {
+	s.composed.options = s.wrap(s.optionsHandler())
+	s.composed.globalOptions = s.wrap(s.globalOptionsHandler())
+	s.composed.notFound = s.wrap(s.notFoundHandler())
+	s.composed.methodNotAllowed = s.wrap(s.methodNotAllowedHandler())
+	s.composed.notAcceptable = s.wrap(respondNotAcceptable)
+	s.composed.unsupportedMediaType = s.wrap(respondUnsupportedMediaType)
+}
+
+// wrap composes the handler with the middleware of the settings and the
+// given route middleware, the first middleware is the outermost.
+func (s *settings) wrap(handler HandlerFunc, mw ...Middleware) HandlerFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		handler = mw[i](handler)
+	}
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restParam is the name of the catch-all param registered by Mount, it can't
+// be declared in the prefix.
+const restParam = "_rest"
+
+// methods are the methods registered by Mount.
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+// mounted is the context value of a request served by a mounted handler.
+type mounted struct {
+	// the stripped prefixes of the mounts
+	prefix string
+	// the Mux that the handler is mounted on and the request it received
+	mux *Mux
+	req *http.Request
+}
+
+// Mount registers the handler for all standard methods on the prefix and the
+// paths under it. The handler is called with the prefix stripped from the
+// request path, use PrefixFromContext to get the stripped prefix.
+//
+//  api := mux.New()
+//  api.Get("/tasks/:ID", getTask)
+//  router.Mount("/api/v1", api, auth) // GET /api/v1/tasks/123 -> GET /tasks/123
+//
+// The standard methods are registered on the prefix, requests with other
+// methods, such as PROPFIND of WebDAV, are routed to the handler too if no
+// route is registered with the method.
+//
+// If the handler is a Mux without NotFound (MethodNotAllowed) or Otherwise
+// handler, its not found (method not allowed) responses are made by the Mux it
+// is mounted on, with the original request.
+func (m *Mux) Mount(prefix string, handler http.Handler, mw ...Middleware) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	for _, segment := range strings.Split(prefix, "/") {
+		name := strings.TrimPrefix(segment, ":")
+		if i := strings.IndexFunc(name, isParamSuffix); i >= 0 {
+			name = name[:i]
+		}
+		if name == restParam && name != segment {
+			panic(fmt.Errorf(`param "%s" can't be declared in mount prefix "%s"`, restParam, prefix))
+		}
+	}
+	// the prefix is stripped by its number of segments in the matched path,
+	// so it doesn't rely on the values of params
+	depth := strings.Count(prefix, "/")
+	mount := func(w http.ResponseWriter, req *http.Request, params Params) {
+		delete(params, restParam)
+		path, rawPath := req.URL.Path, req.URL.EscapedPath()
+		var stripped string
+		if m.load().escaped {
+			// the unescaping of an escaped path never fails
+			i := segmentsEnd(rawPath, depth)
+			stripped, _ = url.PathUnescape(rawPath[:i])
+			rawPath = rawPath[i:]
+		} else {
+			stripped = path[:segmentsEnd(path, depth)]
+			rawPath = stripRawPath(rawPath, depth, path[len(stripped):])
+		}
+		mt := &mounted{prefix: stripped, mux: m, req: req}
+		if outer := mountFromContext(req.Context()); outer != nil {
+			mt.prefix = outer.prefix + stripped
+		}
+
+		u := *req.URL
+		u.Path, u.RawPath = "/", ""
+		if path = path[len(stripped):]; path != "" {
+			u.Path = path
+		}
+		if req.URL.RawPath != "" && rawPath != "" {
+			u.RawPath = rawPath
+		}
+		r := withParams(req.WithContext(context.WithValue(req.Context(), mountKey, mt)), nil, params)
+		r.URL = &u
+		handler.ServeHTTP(w, r)
+	}
+
+	patterns := []string{prefix + "/", prefix + "/:" + restParam + "*"}
+	if prefix != "" {
+		patterns = append(patterns, prefix)
+	}
+	t := m.load()
+	for _, pattern := range patterns {
+		var route *Route
+		for _, method := range methods {
+			route = m.Handle(method, pattern, mount, mw...)
+		}
+		t.mounts[route.Node()] = m.wrap(mount, mw...)
+	}
+}
+
+// Mount registers the handler on the prefixed path in the Mux, see Mux.Mount.
+func (g *Group) Mount(prefix string, handler http.Handler, mw ...Middleware) {
+	g.mux.Mount(g.prefix+prefix, handler, g.join(mw)...)
+}
+
+// PrefixFromContext returns the path prefix stripped by Mount, or an empty
+// string if the request is not served by a mounted handler. It is useful to
+// build absolute URLs in the mounted handler.
+//
+//  url := mux.PrefixFromContext(req.Context()) + "/tasks/123" // "/api/v1/tasks/123"
+//
+func PrefixFromContext(ctx context.Context) string {
+	if mt := mountFromContext(ctx); mt != nil {
+		return mt.prefix
+	}
+	return ""
+}
+
+func mountFromContext(ctx context.Context) *mounted {
+	mt, _ := ctx.Value(mountKey).(*mounted)
+	return mt
+}
+
+// fallback returns the handler of the outer Mux for the request not handled by
+// the mounted Mux, and the request the outer Mux received.
+func (mt *mounted) fallback(notAllowed bool) (HandlerFunc, *http.Request) {
+	m := mt.mux
+	if outer := mountFromContext(mt.req.Context()); outer != nil &&
+		(notAllowed && !m.handlesMethodNotAllowed() || !notAllowed && !m.handlesNotFound()) {
+		return outer.fallback(notAllowed)
+	}
+	if notAllowed {
+		return m.methodNotAllowedHandler(), mt.req
+	}
+	return m.notFoundHandler(), mt.req
+}
+
+// segmentsEnd returns the end index of the first n segments of the path.
+func segmentsEnd(path string, n int) int {
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return len(path)
+}
+
+// stripRawPath strips n segments from the raw path, it returns an empty
+// string if the result is not the encoding of the stripped path.
+func stripRawPath(rawPath string, n int, path string) string {
+	rawPath = rawPath[segmentsEnd(rawPath, n):]
+	if p, err := url.PathUnescape(rawPath); err != nil || p != path {
+		return ""
+	}
+	return rawPath
+}
+
+// isParamSuffix reports whether r may follow the param name in a segment,
+// e.g. "*" in ":path*" or "<" in ":ID<int>".
+func isParamSuffix(r rune) bool {
+	return !(r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+}
@@ -1,13 +1,24 @@
 package mux
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/teambition/trie-mux"
 )
 
+// Errors returned by the Mux.
+var (
+	// ErrInvalidMethod is returned by Mux.HandleE when the method is empty.
+	ErrInvalidMethod = errors.New("invalid method")
+	// ErrRouteNotFound is returned by Mux.URL when no route has the name.
+	ErrRouteNotFound = errors.New("route not found")
+)
+
 // Params represents named parameter values
 type Params map[string]string
 
@@ -18,55 +29,86 @@ type HandlerFunc func(http.ResponseWriter, *http.Request, Params)
 
 // Mux is a tire base HTTP request router which can be used to
 // dispatch requests to different handler functions.
+//
+// Routes should be registered before serving. To change the routes of a
+// serving Mux, use Mux.Update or Mux.Swap.
 type Mux struct {
-	trie      *trie.Trie
-	otherwise HandlerFunc
+	mu    sync.Mutex
+	table atomic.Value // *table
+	*settings
+}
+
+// settings holds the configuration of a Mux other than its routes, it is
+// shared with the host Muxes.
+type settings struct {
+	otherwise, notFound HandlerFunc
+	globalOptions       HandlerFunc
+	methodNotAllowed    MethodNotAllowedFunc
+	autoOptions         OptionsFunc
+	redirect            RedirectFunc
+	redirectPolicy      RedirectPolicy
+	notFoundStatus      int
+	autoHead            bool
+	middleware          []Middleware
+	// whether the settings are of the Mux passed to the fn of Mux.Update
+	updating bool
+	// handlers composed with the middleware
+	composed struct {
+		options, globalOptions, notFound, methodNotAllowed HandlerFunc
+		notAcceptable, unsupportedMediaType                HandlerFunc
+	}
 }
 
 // New returns a Mux instance.
 func New(opts ...trie.Options) *Mux {
-	return &Mux{trie: trie.New(opts...)}
+	m := &Mux{settings: new(settings)}
+	m.table.Store(newTable(trie.New(opts...)))
+	m.compose()
+	return m
 }
 
 // Get registers a new GET route for a path with matching handler in the Mux.
-func (m *Mux) Get(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodGet, pattern, handler)
+func (m *Mux) Get(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodGet, pattern, handler, mw...)
 }
 
 // Head registers a new HEAD route for a path with matching handler in the Mux.
-func (m *Mux) Head(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodHead, pattern, handler)
+func (m *Mux) Head(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodHead, pattern, handler, mw...)
 }
 
 // Post registers a new POST route for a path with matching handler in the Mux.
-func (m *Mux) Post(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodPost, pattern, handler)
+func (m *Mux) Post(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodPost, pattern, handler, mw...)
 }
 
 // Put registers a new PUT route for a path with matching handler in the Mux.
-func (m *Mux) Put(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodPut, pattern, handler)
+func (m *Mux) Put(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodPut, pattern, handler, mw...)
 }
 
 // Patch registers a new PATCH route for a path with matching handler in the Mux.
-func (m *Mux) Patch(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodPatch, pattern, handler)
+func (m *Mux) Patch(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodPatch, pattern, handler, mw...)
 }
 
 // Delete registers a new DELETE route for a path with matching handler in the Mux.
-func (m *Mux) Delete(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodDelete, pattern, handler)
+func (m *Mux) Delete(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodDelete, pattern, handler, mw...)
 }
 
 // Options registers a new OPTIONS route for a path with matching handler in the Mux.
-func (m *Mux) Options(pattern string, handler HandlerFunc) {
-	m.Handle(http.MethodOptions, pattern, handler)
+func (m *Mux) Options(pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	return m.Handle(http.MethodOptions, pattern, handler, mw...)
 }
 
 // Otherwise registers a new handler in the Mux
 // that will run if there is no other handler matching.
+// NotFound and MethodNotAllowed handlers take precedence over it.
 func (m *Mux) Otherwise(handler HandlerFunc) {
+	m.configure()
 	m.otherwise = handler
+	m.compose()
 }
 
 // Handle registers a new handler with method and path in the Mux.
@@ -76,73 +118,194 @@ func (m *Mux) Otherwise(handler HandlerFunc) {
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (m *Mux) Handle(method, pattern string, handler HandlerFunc) {
+//
+// The route middleware wrap the handler inside the middleware of the Mux.
+// It returns the registered Route, which can be named for later lookup:
+//
+//  mux.Handle("POST", `/api/task/:ID(^\d+$)+:cancel`, handler, auth).Name("task.cancel")
+//
+func (m *Mux) Handle(method, pattern string, handler HandlerFunc, mw ...Middleware) *Route {
+	route, err := m.HandleE(method, pattern, handler, mw...)
+	if err != nil {
+		panic(err)
+	}
+	return route
+}
+
+// HandleE is like Handle but returns an error instead of panicking, so that
+// routes loaded at runtime can be validated and all bad ones reported.
+// The error is ErrInvalidMethod or a *trie.PatternError.
+func (m *Mux) HandleE(method, pattern string, handler HandlerFunc, mw ...Middleware) (*Route, error) {
 	if method == "" {
-		panic(fmt.Errorf("invalid method"))
+		return nil, ErrInvalidMethod
+	}
+	t := m.load()
+	node, err := t.trie.DefineE(pattern)
+	if err != nil {
+		return nil, err
 	}
-	m.trie.Define(pattern).Handle(strings.ToUpper(method), handler)
+	method = strings.ToUpper(method)
+	v, err := handle(node, method, m.wrap(handler, mw...))
+	if err != nil {
+		return nil, err
+	}
+	return &Route{table: t, node: node, method: method, variant: v}, nil
+}
+
+// Route returns the route registered with the name, or nil if not found or
+// its handler is removed.
+func (m *Mux) Route(name string) *Route {
+	if route := m.load().routes[name]; route != nil && route.handled() {
+		return route
+	}
+	return nil
+}
+
+// URL builds a URL path for the route registered with the name.
+//
+//  mux.URL("task.cancel", mux.Params{"ID": "123"})
+//  // "/api/task/123:cancel", nil
+//
+func (m *Mux) URL(name string, params Params) (string, error) {
+	route := m.Route(name)
+	if route == nil {
+		return "", fmt.Errorf(`%w: "%s"`, ErrRouteNotFound, name)
+	}
+	return route.URL(params)
+}
+
+// Lookup returns the trie node defined for the pattern, or nil if the pattern
+// is not defined. Use Node.URL to build a URL path for it.
+//
+//  mux.Lookup("/view/:view").URL(map[string]string{"view": "users"})
+//  // "/view/users", nil
+//
+func (m *Mux) Lookup(pattern string) *trie.Node {
+	return m.load().trie.Lookup(pattern)
+}
+
+// GetEndpoints returns all endpoint nodes of the Mux.
+func (m *Mux) GetEndpoints() []*trie.Node {
+	return m.load().trie.GetEndpoints()
 }
 
 // Handler is an adapter which allows the usage of an http.Handler as a
-// request handle.
-func (m *Mux) Handler(method, path string, handler http.Handler) {
-	m.Handle(method, path, func(w http.ResponseWriter, req *http.Request, _ Params) {
-		handler.ServeHTTP(w, req)
-	})
+// request handle. The params and the route are stored in the request context,
+// use ParamsFromContext and RouteFromContext to get them.
+func (m *Mux) Handler(method, path string, handler http.Handler, mw ...Middleware) *Route {
+	var route *Route
+	route = m.Handle(method, path, func(w http.ResponseWriter, req *http.Request, params Params) {
+		handler.ServeHTTP(w, withParams(req, route, params))
+	}, mw...)
+	return route
 }
 
 // HandlerFunc is an adapter which allows the usage of an http.HandlerFunc as a
 // request handle.
-func (m *Mux) HandlerFunc(method, path string, handler http.HandlerFunc) {
-	m.Handler(method, path, handler)
+func (m *Mux) HandlerFunc(method, path string, handler http.HandlerFunc, mw ...Middleware) *Route {
+	return m.Handler(method, path, handler, mw...)
 }
 
 // ServeHTTP implemented http.Handler interface
 func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var handler HandlerFunc
+	t := m.load()
+	if t.hosts != nil {
+		res := trie.AcquireMatched()
+		defer trie.ReleaseMatched(res)
+		t.hosts.MatchInto(hostPath(req.Host), res)
+		if res.Node != nil {
+			res.Node.GetHandler(hostKey).(*Mux).serve(w, req, hostParams(res.Values))
+			return
+		}
+	}
+	m.serve(w, req, nil)
+}
+
+// serve serves the request with the routes of the Mux, host is the params of
+// the matched host.
+func (m *Mux) serve(w http.ResponseWriter, req *http.Request, host trie.Params) {
+	t := m.load()
 	path := req.URL.Path
+	if t.escaped {
+		path = req.URL.EscapedPath()
+	}
 	method := req.Method
-	res := m.trie.Match(path)
+	res := trie.AcquireMatched()
+	defer trie.ReleaseMatched(res)
+	// a path not start with "/" is not matched
+	t.trie.MatchInto(path, res)
 
 	if res.Node == nil {
-		// FixedPathRedirect or TrailingSlashRedirect
-		if res.TSR != "" || res.FPR != "" {
-			req.URL.Path = res.TSR
-			if res.FPR != "" {
-				req.URL.Path = res.FPR
-			}
-			code := 301
-			if method != "GET" {
-				code = 307
-			}
-			http.Redirect(w, req, req.URL.String(), code)
+		if path == "*" && method == http.MethodOptions {
+			w.Header().Set("Allow", m.getMethods())
+			m.composed.globalOptions(w, req, nil)
 			return
 		}
 
-		if m.otherwise == nil {
-			http.Error(w, fmt.Sprintf(`"%s" not implemented`, path), 501)
+		// FixedPathRedirect, TrailingSlashRedirect or CaseInsensitiveRedirect
+		switch {
+		case res.FPR != "" && m.redirectTo(w, req, host, res.FPR, true):
+			return
+		case res.TSR != "" && m.redirectTo(w, req, host, res.TSR, false):
+			return
+		case res.CIR != "" && m.redirectTo(w, req, host, res.CIR, true):
 			return
 		}
-		handler = m.otherwise
-	} else {
-		ok := false
-		if handler, ok = res.Node.GetHandler(method).(HandlerFunc); !ok {
-			// OPTIONS support
-			if method == http.MethodOptions {
-				w.Header().Set("Allow", res.Node.GetAllow())
-				w.WriteHeader(204)
-				return
-			}
-
-			if m.otherwise == nil {
-				// If no route handler is returned, it's a 405 error
-				w.Header().Set("Allow", res.Node.GetAllow())
-				http.Error(w, fmt.Sprintf(`"%s" not allowed in "%s"`, method, path), 405)
-				return
-			}
-			handler = m.otherwise
+
+		handler := m.composed.notFound
+		if mt := mountFromContext(req.Context()); mt != nil && !m.handlesNotFound() {
+			handler, req = mt.fallback(false)
+			res.Values = res.Values[:0]
 		}
+		handler(w, req, newParams(host, res.Values))
+		return
 	}
+	m.serveNode(w, req, t, host, res)
+}
+
+// serveNode serves the request with the handlers of the matched node.
+func (m *Mux) serveNode(w http.ResponseWriter, req *http.Request, t *table, host trie.Params, res *trie.Matched) {
+	method := req.Method
+	handler, ok := m.getHandler(res.Node, method, req)
+	if !ok && method == http.MethodHead && m.autoHead {
+		if handler, ok = m.getHandler(res.Node, http.MethodGet, req); ok {
+			hw := &headWriter{ResponseWriter: w}
+			defer hw.send(true)
+			w = hw
+		}
+	}
+	if !ok {
+		// the methods not registered by Mount
+		handler, ok = t.mounts[res.Node]
+	}
+	if !ok {
+		w.Header().Set("Allow", m.getAllow(res.Node))
+		switch mt := mountFromContext(req.Context()); {
+		// OPTIONS support
+		case method == http.MethodOptions:
+			handler = m.composed.options
+		case mt != nil && !m.handlesMethodNotAllowed():
+			handler, req = mt.fallback(true)
+			res.Values = res.Values[:0]
+		default:
+			// If no route handler is returned, it's a 405 error
+			handler = m.composed.methodNotAllowed
+		}
+	}
+	handler(w, req, newParams(host, res.Values))
+}
 
-	handler(w, req, res.Params)
+// newParams returns the params of the matched host and path.
+func newParams(host, values trie.Params) Params {
+	if len(values)+len(host) == 0 {
+		return nil
+	}
+	params := make(Params, len(values)+len(host))
+	for _, param := range host {
+		params[param.Name] = param.Value
+	}
+	for _, param := range values {
+		params[param.Name] = param.Value
+	}
+	return params
 }
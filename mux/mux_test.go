@@ -1,11 +1,16 @@
 package mux
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/teambition/trie-mux"
@@ -19,6 +24,14 @@ func Request(method, url string, body io.Reader) (*http.Response, error) {
 	return http.DefaultClient.Do(req)
 }
 
+func EqualPtr(t *testing.T, a, b interface{}) {
+	assert.Equal(t, reflect.ValueOf(a).Pointer(), reflect.ValueOf(b).Pointer())
+}
+
+func NotEqualPtr(t *testing.T, a, b interface{}) {
+	assert.NotEqual(t, reflect.ValueOf(a).Pointer(), reflect.ValueOf(b).Pointer())
+}
+
 func TestMux(t *testing.T) {
 
 	t.Run("Mux.Handle", func(t *testing.T) {
@@ -63,6 +76,1028 @@ func TestMux(t *testing.T) {
 		res.Body.Close()
 	})
 
+	t.Run("Mux.HandleE", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(204)
+		}
+		mux := New()
+		_, err := mux.HandleE("", "/a", handler)
+		assert.Equal(ErrInvalidMethod, err)
+		route, err := mux.HandleE("GET", "/:type", handler)
+		assert.Nil(err)
+		assert.Equal("GET", route.GetMethod())
+		_, err = mux.HandleE("GET", "/:type1", handler)
+		assert.True(errors.Is(err, trie.ErrConflict))
+		_, err = mux.HandleE("get", "/:type", handler)
+		assert.True(errors.Is(err, trie.ErrDuplicateHandler))
+		_, err = mux.HandleE("GET", "/a//b", handler)
+		assert.True(errors.Is(err, trie.ErrInvalidPattern))
+	})
+
+	t.Run("Mux.Lookup", func(t *testing.T) {
+		assert := assert.New(t)
+
+		mux := New()
+		mux.Get("/view/:view", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(204)
+		})
+		assert.Nil(mux.Lookup("/view"))
+		url, err := mux.Lookup("/view/:view").URL(map[string]string{"view": "users"})
+		assert.Nil(err)
+		assert.Equal("/view/users", url)
+	})
+
+	t.Run("named routes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(204)
+		}
+		mux := New()
+		route := mux.Post(`/api/:resource/:ID(^\d+$)+:cancel`, handler).Name("task.cancel")
+		assert.Equal("task.cancel", route.GetName())
+		assert.Equal("POST", route.GetMethod())
+		assert.Equal(`/api/:resource/:ID(^\d+$)+:cancel`, route.GetPattern())
+		EqualPtr(t, route, mux.Route("task.cancel"))
+		EqualPtr(t, route.Node(), mux.Lookup(`/api/:resource/:ID(^\d+$)+:cancel`))
+		assert.Nil(mux.Route("task"))
+
+		url, err := mux.URL("task.cancel", Params{"resource": "task", "ID": "123"})
+		assert.Nil(err)
+		assert.Equal("/api/task/123:cancel", url)
+		_, err = mux.URL("task.cancel", Params{"resource": "task", "ID": "abc"})
+		assert.True(errors.Is(err, trie.ErrInvalidParam))
+		_, err = mux.URL("task", nil)
+		assert.True(errors.Is(err, ErrRouteNotFound))
+
+		view := mux.Get("/view/:view", handler).Name("view")
+		assert.Panics(func() {
+			mux.Get("/views", handler).Name("view")
+		})
+		view.Name("view.get")
+		assert.Nil(mux.Route("view"))
+		EqualPtr(t, view, mux.Route("view.get"))
+		EqualPtr(t, view, view.Name("view.get"))
+	})
+
+	t.Run("Route.Meta", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {}
+		mux := New()
+		route := mux.Get("/api/:type", handler).Meta("list")
+		mux.Post("/api/:type", handler)
+		assert.Equal("list", route.GetMeta())
+		assert.Equal("list", mux.Lookup("/api/:type").GetMeta("GET"))
+		assert.Nil(mux.Lookup("/api/:type").GetMeta("POST"))
+
+		endpoints := mux.GetEndpoints()
+		assert.Equal(1, len(endpoints))
+		EqualPtr(t, route.Node(), endpoints[0])
+	})
+	t.Run("Mux.Use and route middleware", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tag := func(name string) Middleware {
+			return func(next HandlerFunc) HandlerFunc {
+				return func(w http.ResponseWriter, req *http.Request, params Params) {
+					w.Header().Add("x-mw", name)
+					next(w, req, params)
+				}
+			}
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+		}
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+
+		mux := New()
+		mux.Get("/before", handler)
+		mux.Use(tag("a"), tag("b"))
+		mux.Get("/api/:type", handler, tag("c"))
+		mux.Use(tag("d"))
+		mux.Handle("PUT", "/api/:type", handler)
+		mux.HandlerFunc("POST", "/api/:type", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(201)
+		}, tag("e"))
+
+		res := serve(mux, "GET", "/before")
+		assert.Equal(200, res.Code)
+		assert.Nil(res.Header()["X-Mw"])
+
+		res = serve(mux, "GET", "/api/task")
+		assert.Equal(200, res.Code)
+		assert.Equal([]string{"a", "b", "c"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "PUT", "/api/task")
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "POST", "/api/task")
+		assert.Equal(201, res.Code)
+		assert.Equal([]string{"a", "b", "d", "e"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "OPTIONS", "/api/task")
+		assert.Equal(204, res.Code)
+		assert.Equal("GET, PUT, POST", res.Header().Get("Allow"))
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "DELETE", "/api/task")
+		assert.Equal(405, res.Code)
+		assert.Equal("GET, PUT, POST", res.Header().Get("Allow"))
+		assert.Equal(`"DELETE" not allowed in "/api/task"`+"\n", res.Body.String())
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "GET", "/none/x")
+		assert.Equal(501, res.Code)
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		mux.Otherwise(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(404)
+		})
+		res = serve(mux, "GET", "/none/x")
+		assert.Equal(404, res.Code)
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+		res = serve(mux, "DELETE", "/api/task")
+		assert.Equal(404, res.Code)
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		// only the routes can be changed in Update
+		assert.Nil(mux.Update(func(m *Mux) error {
+			assert.Panics(func() { m.Use(tag("f")) })
+			assert.Panics(func() { m.NotFound(handler) })
+			assert.Panics(func() { m.Host("example.com").RedirectPolicy(RedirectPolicy{}) })
+			m.Get("/after", handler)
+			return nil
+		}))
+		res = serve(mux, "GET", "/after")
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+		res = serve(mux, "GET", "/none/x")
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+		mux.Use(tag("f"))
+		res = serve(mux, "GET", "/none/x")
+		assert.Equal([]string{"a", "b", "d", "f"}, res.Header()["X-Mw"])
+	})
+	t.Run("Mux.Group", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tag := func(name string) Middleware {
+			return func(next HandlerFunc) HandlerFunc {
+				return func(w http.ResponseWriter, req *http.Request, params Params) {
+					w.Header().Add("x-mw", name)
+					next(w, req, params)
+				}
+			}
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+			w.Write([]byte(params["owner"] + "/" + params["repo"] + "/" + params["ID"]))
+		}
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+
+		mux := New()
+		mux.Use(tag("a"))
+		repo := mux.Group("/api/v1/repos/:owner/:repo/", tag("b"))
+		repo.Get("", handler)
+		route := repo.Get("/issues/:ID(^\\d+$)", handler, tag("c"))
+		assert.Equal("/api/v1/repos/:owner/:repo/issues/:ID(^\\d+$)", route.GetPattern())
+		pulls := repo.Group("/pulls", tag("d"))
+		repo.Use(tag("e"))
+		pulls.Post("/:ID", handler)
+		repo.HandlerFunc("DELETE", "", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(204)
+		})
+
+		res := serve(mux, "GET", "/api/v1/repos/teambition/trie-mux")
+		assert.Equal(200, res.Code)
+		assert.Equal("teambition/trie-mux/", res.Body.String())
+		assert.Equal([]string{"a", "b"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "GET", "/api/v1/repos/teambition/trie-mux/issues/1")
+		assert.Equal("teambition/trie-mux/1", res.Body.String())
+		assert.Equal([]string{"a", "b", "c"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "POST", "/api/v1/repos/teambition/trie-mux/pulls/2")
+		assert.Equal("teambition/trie-mux/2", res.Body.String())
+		assert.Equal([]string{"a", "b", "d"}, res.Header()["X-Mw"])
+
+		res = serve(mux, "DELETE", "/api/v1/repos/teambition/trie-mux")
+		assert.Equal(204, res.Code)
+		assert.Equal([]string{"a", "b", "e"}, res.Header()["X-Mw"])
+
+		_, err := mux.Group("/api/v1/repos/:user").HandleE("GET", "/stars", handler)
+		assert.True(errors.Is(err, trie.ErrConflict))
+		assert.Equal(`invalid pattern name "user", as prev defined "/api/v1/repos/:owner"`, err.Error())
+		assert.Panics(func() {
+			repo.Group("/pulls").Get("/:number", handler)
+		})
+	})
+	t.Run("Mux.Mount", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+
+		inner := New()
+		inner.Get("/tasks/:ID", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+			w.Write([]byte(PrefixFromContext(req.Context()) + " " + req.URL.Path + " " + req.URL.RawPath + " " + params["ID"]))
+		})
+		inner.Get("/", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+			w.Write([]byte(PrefixFromContext(req.Context()) + " " + req.URL.Path))
+		})
+		inner.Get("/list/", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+		})
+
+		mux := New()
+		mux.Mount("/api/:version/", inner)
+		mux.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(200)
+			w.Write([]byte(req.Method + " " + req.URL.Path))
+		}))
+		mux.Group("/v2").Mount("/", inner)
+
+		res := serve(mux, "GET", "/api/v1/tasks/123")
+		assert.Equal(200, res.Code)
+		assert.Equal("/api/v1 /tasks/123  123", res.Body.String())
+		res = serve(mux, "GET", "/api/v1/tasks/%41")
+		assert.Equal("/api/v1 /tasks/A /tasks/%41 A", res.Body.String())
+		res = serve(mux, "GET", "/api/v1")
+		assert.Equal("/api/v1 /", res.Body.String())
+		res = serve(mux, "GET", "/api/v1/")
+		assert.Equal("/api/v1 /", res.Body.String())
+		res = serve(mux, "GET", "/v2/tasks/1")
+		assert.Equal("/v2 /tasks/1  1", res.Body.String())
+		res = serve(mux, "DELETE", "/files/a/b")
+		assert.Equal(200, res.Code)
+		assert.Equal("DELETE /a/b", res.Body.String())
+
+		// the methods not registered are routed to the mounted handler
+		res = serve(mux, "PROPFIND", "/files/a/b")
+		assert.Equal(200, res.Code)
+		assert.Equal("PROPFIND /a/b", res.Body.String())
+		assert.Equal("REPORT /", serve(mux, "REPORT", "/files").Body.String())
+		assert.Nil(mux.Update(func(m *Mux) error { return nil }))
+		assert.Equal("PROPFIND /a", serve(mux, "PROPFIND", "/files/a").Body.String())
+
+		// a prefix param may be named "rest"
+		mux.Mount("/r/:rest", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			params := ParamsFromContext(req.Context())
+			w.WriteHeader(200)
+			w.Write([]byte(PrefixFromContext(req.Context()) + " " + req.URL.Path + " " + params["rest"] + " " + params[restParam]))
+		}))
+		res = serve(mux, "GET", "/r/abc/")
+		assert.Equal("/r/abc / abc ", res.Body.String())
+		res = serve(mux, "GET", "/r/abc")
+		assert.Equal("/r/abc / abc ", res.Body.String())
+		res = serve(mux, "GET", "/r/abc/x/y")
+		assert.Equal("/r/abc /x/y abc ", res.Body.String())
+		res = serve(mux, "GET", "/r/a%2Fb/x")
+		assert.Equal("/r/a /b/x a ", res.Body.String())
+		assert.Panics(func() { mux.Mount("/s/:_rest", inner) })
+		assert.Panics(func() { mux.Mount("/s/:_rest<int>", inner) })
+
+		res = serve(mux, "GET", "/api/v1/list")
+		assert.Equal(301, res.Code)
+		assert.Equal("/api/v1/list/", res.Header().Get("Location"))
+
+		// not found and not allowed propagate to the outer Mux
+		res = serve(mux, "GET", "/api/v1/none")
+		assert.Equal(501, res.Code)
+		assert.Equal(`"/api/v1/none" not implemented`+"\n", res.Body.String())
+		res = serve(mux, "PUT", "/api/v1/tasks/123")
+		assert.Equal(405, res.Code)
+		assert.Equal("GET", res.Header().Get("Allow"))
+		assert.Equal(`"PUT" not allowed in "/api/v1/tasks/123"`+"\n", res.Body.String())
+		res = serve(mux, "OPTIONS", "/api/v1/tasks/123")
+		assert.Equal(204, res.Code)
+		assert.Equal("GET", res.Header().Get("Allow"))
+
+		mux.Otherwise(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(404)
+			w.Write([]byte(req.URL.Path))
+		})
+		res = serve(mux, "GET", "/api/v1/none")
+		assert.Equal(404, res.Code)
+		assert.Equal("/api/v1/none", res.Body.String())
+		res = serve(mux, "PUT", "/api/v1/tasks/123")
+		assert.Equal(404, res.Code)
+
+		inner.Otherwise(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(410)
+		})
+		res = serve(mux, "GET", "/api/v1/none")
+		assert.Equal(410, res.Code)
+	})
+	t.Run("ParamsFromContext and RouteFromContext", func(t *testing.T) {
+		assert := assert.New(t)
+
+		mux := New()
+		mux.HandlerFunc("GET", "/api/:type/:ID", func(w http.ResponseWriter, req *http.Request) {
+			params := ParamsFromContext(req.Context())
+			route := RouteFromContext(req.Context())
+			w.WriteHeader(200)
+			w.Write([]byte(params["type"] + " " + params["ID"] + " " + route.GetName() + " " + route.GetPattern()))
+		}).Name("getResource")
+		mux.Mount("/files/:bucket", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(200)
+			w.Write([]byte(ParamsFromContext(req.Context())["bucket"]))
+			assert.Nil(RouteFromContext(req.Context()))
+		}))
+		mux.Get("/", func(w http.ResponseWriter, req *http.Request, params Params) {
+			assert.Nil(ParamsFromContext(req.Context()))
+			assert.Nil(RouteFromContext(req.Context()))
+			w.WriteHeader(204)
+		})
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest("GET", "/api/task/123", nil))
+		assert.Equal("task 123 getResource /api/:type/:ID", res.Body.String())
+
+		res = httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest("GET", "/files/b1/a.txt", nil))
+		assert.Equal("b1", res.Body.String())
+
+		res = httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(204, res.Code)
+	})
+	t.Run("Mux.NotFound, Mux.MethodNotAllowed and Mux.Redirect", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+		}
+
+		mux := New()
+		mux.Get("/api/:type", handler)
+		mux.Post("/api/:type", handler)
+		mux.NotFoundStatus(404)
+		res := serve(mux, "GET", "/none")
+		assert.Equal(404, res.Code)
+		assert.Equal(`"/none" not found`+"\n", res.Body.String())
+		assert.Panics(func() { mux.NotFoundStatus(410) })
+		assert.Equal(404, serve(mux, "GET", "/none").Code)
+		mux.NotFoundStatus(501)
+		assert.Equal(501, serve(mux, "GET", "/none").Code)
+		mux.NotFoundStatus(404)
+
+		mux.Otherwise(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(418)
+		})
+		mux.NotFound(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error":"NotFound"}`))
+		})
+		res = serve(mux, "GET", "/none")
+		assert.Equal(404, res.Code)
+		assert.Equal(`{"error":"NotFound"}`, res.Body.String())
+		res = serve(mux, "PUT", "/api/task")
+		assert.Equal(418, res.Code)
+
+		mux.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request, allow string) {
+			w.WriteHeader(405)
+			w.Write([]byte(allow))
+		})
+		res = serve(mux, "PUT", "/api/task")
+		assert.Equal(405, res.Code)
+		assert.Equal("GET, POST", res.Header().Get("Allow"))
+		assert.Equal("GET, POST", res.Body.String())
+		res = serve(mux, "OPTIONS", "/api/task")
+		assert.Equal(204, res.Code)
+
+		mux.Redirect(func(w http.ResponseWriter, req *http.Request, url string, code int) {
+			w.WriteHeader(404)
+			w.Write([]byte(fmt.Sprintf("%d %s", code, url)))
+		})
+		res = serve(mux, "POST", "/api/task/")
+		assert.Equal(404, res.Code)
+		assert.Equal("307 /api/task", res.Body.String())
+
+		// a mounted Mux with handlers does not propagate
+		inner := New()
+		inner.Get("/a", handler)
+		inner.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request, allow string) {
+			w.WriteHeader(400)
+		})
+		mux.Mount("/inner", inner)
+		res = serve(mux, "PUT", "/inner/a")
+		assert.Equal(400, res.Code)
+		res = serve(mux, "GET", "/inner/b")
+		assert.Equal(`{"error":"NotFound"}`, res.Body.String())
+	})
+	t.Run("Mux.RedirectPolicy", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+			w.Write([]byte(req.Method + " " + req.URL.String()))
+		}
+		mux := New()
+		for _, method := range []string{"GET", "HEAD", "POST"} {
+			mux.Handle(method, "/api/:type", handler)
+		}
+
+		// default policy
+		res := serve(mux, "HEAD", "/api/task/")
+		assert.Equal(301, res.Code)
+		assert.Equal("/api/task", res.Header().Get("Location"))
+		res = serve(mux, "POST", "/api//task")
+		assert.Equal(307, res.Code)
+		assert.Equal("/api/task", res.Header().Get("Location"))
+
+		mux.RedirectPolicy(RedirectPolicy{
+			TrailingSlash:       302,
+			TrailingSlashUnsafe: 308,
+			FixedPath:           308,
+			FixedPathUnsafe:     307,
+		})
+		for _, c := range []struct {
+			method, path string
+			code         int
+		}{
+			{"GET", "/api/task/", 302},
+			{"HEAD", "/api/task/", 302},
+			{"POST", "/api/task/", 308},
+			{"GET", "/api//task?a=1", 308},
+			{"HEAD", "/api//task/", 308},
+			{"POST", "/api//task", 307},
+		} {
+			res = serve(mux, c.method, c.path)
+			assert.Equal(c.code, res.Code, c.method+" "+c.path)
+		}
+		assert.Equal("/api/task?a=1", serve(mux, "GET", "/api//task?a=1").Header().Get("Location"))
+
+		mux.RedirectPolicy(RedirectPolicy{Serve: true})
+		res = serve(mux, "POST", "/api//task/?a=1")
+		assert.Equal(200, res.Code)
+		assert.Equal("POST /api/task?a=1", res.Body.String())
+		res = serve(mux, "GET", "/api/task/")
+		assert.Equal(200, res.Code)
+		assert.Equal("GET /api/task", res.Body.String())
+
+		mux.RedirectPolicy(RedirectPolicy{Veto: func(req *http.Request, url string) bool {
+			return req.Method != "GET"
+		}})
+		res = serve(mux, "POST", "/api/task/")
+		assert.Equal(501, res.Code)
+		assert.Equal(`"/api/task/" not implemented`+"\n", res.Body.String())
+		res = serve(mux, "GET", "/api/task/")
+		assert.Equal(301, res.Code)
+
+		// CaseInsensitiveRedirect
+		mux = New(trie.Options{CaseInsensitiveRedirect: true, FixedPathRedirect: true})
+		mux.Handle("GET", "/api/:type", handler)
+		mux.Handle("POST", "/api/:type", handler)
+		res = serve(mux, "GET", "/API/Task?a=1")
+		assert.Equal(301, res.Code)
+		assert.Equal("/api/Task?a=1", res.Header().Get("Location"))
+		res = serve(mux, "POST", "/API//Task")
+		assert.Equal(307, res.Code)
+		assert.Equal("/api/Task", res.Header().Get("Location"))
+		assert.Equal(501, serve(mux, "GET", "/API").Code)
+
+		// the redirect location is served once
+		mux = New(trie.Options{CaseInsensitiveRedirect: true})
+		mux.Get("/a/:x/b", handler)
+		mux.Get("/a/c/d", handler)
+		mux.RedirectPolicy(RedirectPolicy{Serve: true})
+		res = serve(mux, "GET", "/A/c/d")
+		assert.Equal(200, res.Code)
+		assert.Equal("GET /a/c/d", res.Body.String())
+		assert.Equal(501, serve(mux, "GET", "/a/c/b").Code)
+		mux.RedirectPolicy(RedirectPolicy{})
+		assert.Equal(501, serve(mux, "GET", "/a/c/b").Code)
+		res = serve(mux, "GET", "/A/c/d")
+		assert.Equal(301, res.Code)
+		assert.Equal("/a/c/d", res.Header().Get("Location"))
+	})
+	t.Run("Mux.AutoHead", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, path string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			return res
+		}
+		mux := New()
+		mux.Get("/a", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.Header().Set("x-method", req.Method)
+			w.WriteHeader(201)
+			w.Write([]byte("hello"))
+			w.Write([]byte(" world"))
+		})
+		mux.Put("/a", func(w http.ResponseWriter, req *http.Request, params Params) {})
+		mux.Get("/b", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("hello"))
+			w.(http.Flusher).Flush()
+		})
+		mux.Get("/c", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.Write([]byte("GET"))
+		})
+		mux.Head("/c", func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(204)
+		})
+		mux.Put("/d", func(w http.ResponseWriter, req *http.Request, params Params) {})
+
+		res := serve(mux, "HEAD", "/a")
+		assert.Equal(405, res.Code)
+		assert.Equal("GET, PUT", res.Header().Get("Allow"))
+
+		mux.AutoHead(true)
+		res = serve(mux, "HEAD", "/a")
+		assert.Equal(201, res.Code)
+		assert.Equal("HEAD", res.Header().Get("x-method"))
+		assert.Equal("11", res.Header().Get("Content-Length"))
+		assert.Equal("", res.Body.String())
+
+		res = serve(mux, "HEAD", "/b")
+		assert.Equal(200, res.Code)
+		assert.True(res.Flushed)
+		assert.Equal("100", res.Header().Get("Content-Length"))
+		assert.Equal("", res.Body.String())
+
+		res = serve(mux, "HEAD", "/c")
+		assert.Equal(204, res.Code)
+
+		res = serve(mux, "OPTIONS", "/a")
+		assert.Equal("GET, HEAD, PUT", res.Header().Get("Allow"))
+		res = serve(mux, "DELETE", "/a")
+		assert.Equal(405, res.Code)
+		assert.Equal("GET, HEAD, PUT", res.Header().Get("Allow"))
+		res = serve(mux, "DELETE", "/c")
+		assert.Equal("GET, HEAD", res.Header().Get("Allow"))
+		res = serve(mux, "HEAD", "/d")
+		assert.Equal(405, res.Code)
+		assert.Equal("PUT", res.Header().Get("Allow"))
+	})
+	t.Run("Mux.AutoOptions, Mux.GlobalOptions and CORS", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, path string, header http.Header) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(method, path, nil)
+			for key := range header {
+				req.Header.Set(key, header.Get(key))
+			}
+			mux.ServeHTTP(res, req)
+			return res
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+		}
+		mux := New()
+		mux.Get("/a", handler)
+		mux.Post("/a", handler)
+		mux.Put("/b", handler)
+		mux.load().trie.Define("/c")
+
+		res := serve(mux, "OPTIONS", "*", nil)
+		assert.Equal(204, res.Code)
+		assert.Equal("GET, POST, PUT", res.Header().Get("Allow"))
+		mux.AutoHead(true)
+		res = serve(mux, "OPTIONS", "*", nil)
+		assert.Equal("GET, HEAD, POST, PUT", res.Header().Get("Allow"))
+		mux.GlobalOptions(func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+		})
+		res = serve(mux, "OPTIONS", "*", nil)
+		assert.Equal(200, res.Code)
+		assert.Equal("GET, HEAD, POST, PUT", res.Header().Get("Allow"))
+		res = serve(mux, "GET", "*", nil)
+		assert.Equal(501, res.Code)
+
+		cors := CORS{
+			AllowOrigins:     []string{"https://a.com", "https://b.com"},
+			AllowHeaders:     []string{"Content-Type", "Authorization"},
+			ExposeHeaders:    []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		}
+		mux.AutoOptions(cors.Respond)
+		mux.Use(cors.Middleware)
+		mux.Get("/d", handler)
+
+		preflight := http.Header{}
+		preflight.Set("Origin", "https://b.com")
+		preflight.Set("Access-Control-Request-Method", "POST")
+		res = serve(mux, "OPTIONS", "/a", preflight)
+		assert.Equal(204, res.Code)
+		assert.Equal("GET, HEAD, POST", res.Header().Get("Allow"))
+		assert.Equal("https://b.com", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("GET, HEAD, POST", res.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal("Content-Type, Authorization", res.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal("true", res.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal("3600", res.Header().Get("Access-Control-Max-Age"))
+		assert.Equal("Origin", res.Header().Get("Vary"))
+
+		preflight.Set("Origin", "https://c.com")
+		res = serve(mux, "OPTIONS", "/a", preflight)
+		assert.Equal(204, res.Code)
+		assert.Equal("", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("", res.Header().Get("Access-Control-Allow-Methods"))
+
+		res = serve(mux, "OPTIONS", "/b", nil)
+		assert.Equal(204, res.Code)
+		assert.Equal("PUT", res.Header().Get("Allow"))
+		assert.Equal("", res.Header().Get("Access-Control-Allow-Methods"))
+
+		res = serve(mux, "GET", "/d", http.Header{"Origin": {"https://a.com"}})
+		assert.Equal(200, res.Code)
+		assert.Equal("https://a.com", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("X-Request-Id", res.Header().Get("Access-Control-Expose-Headers"))
+
+		any := CORS{AllowOrigins: []string{"*"}}
+		mux.AutoOptions(any.Respond)
+		preflight.Set("Origin", "https://c.com")
+		res = serve(mux, "OPTIONS", "/a", preflight)
+		assert.Equal("*", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal("", res.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal("", res.Header().Get("Access-Control-Max-Age"))
+	})
+	t.Run("Mux.Host", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, url string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, url, nil))
+			return res
+		}
+		handler := func(name string) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, params Params) {
+				w.WriteHeader(200)
+				w.Write([]byte(fmt.Sprintf("%s %s %s %s", name, params["tenant"], params["subdomain"], params["ID"])))
+			}
+		}
+
+		mux := New()
+		mux.Get("/api/:ID", handler("default"))
+		tenant := mux.Host(":tenant.example.com")
+		tenant.Get("/api/:ID", handler("tenant"))
+		EqualPtr(t, tenant, mux.Host(":tenant.example.com"))
+		mux.Host("www.example.com").Get("/api/:ID", handler("www"))
+		mux.Host("*.static.example.com").Get("/:ID", handler("static"))
+
+		assert.Equal("default   1", serve(mux, "GET", "http://other.com/api/1").Body.String())
+		assert.Equal("tenant acme  2", serve(mux, "GET", "http://acme.example.com:8080/api/2").Body.String())
+		assert.Equal("www   3", serve(mux, "GET", "http://WWW.Example.com/api/3").Body.String())
+		assert.Equal("static  a.b 4", serve(mux, "GET", "http://a.b.static.example.com/4").Body.String())
+		assert.Equal("default   5", serve(mux, "GET", "http://example.com/api/5").Body.String())
+		assert.Equal(501, serve(mux, "GET", "http://acme.example.com/none").Code)
+
+		res := serve(mux, "GET", "http://acme.example.com/api/2/")
+		assert.Equal(301, res.Code)
+		assert.Equal("http://acme.example.com/api/2", res.Header().Get("Location"))
+		tenant.RedirectPolicy(RedirectPolicy{Serve: true})
+		assert.Equal("tenant acme  2", serve(mux, "GET", "http://acme.example.com/api/2/").Body.String())
+
+		assert.Nil(mux.Update(func(m *Mux) error {
+			m.Host(":tenant.example.com").Get("/api/:ID/x", handler("x"))
+			return nil
+		}))
+		assert.Equal("x acme  6", serve(mux, "GET", "http://acme.example.com/api/6/x").Body.String())
+		assert.Nil(tenant.Lookup("/api/:ID/x"))
+
+		// the settings configured after Host reach the host routes
+		mux.NotFoundStatus(404)
+		mux.AutoHead(true)
+		mux.Use(func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, params Params) {
+				w.Header().Set("x-mw", "root")
+				next(w, req, params)
+			}
+		})
+		res = serve(mux, "GET", "http://acme.example.com/none")
+		assert.Equal(404, res.Code)
+		assert.Equal("root", res.Header().Get("x-mw"))
+		assert.Equal(200, serve(mux, "HEAD", "http://acme.example.com/api/2").Code)
+
+		next := New()
+		next.Host(":tenant.example.com").Get("/api/:ID", handler("next"))
+		mux.Swap(next)
+		mux.NotFoundStatus(501)
+		assert.Equal("next acme  7", serve(mux, "GET", "http://acme.example.com/api/7").Body.String())
+		assert.Equal(501, serve(mux, "GET", "http://acme.example.com/none").Code)
+	})
+	t.Run("Route matchers", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, url string, header map[string]string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			req := httptest.NewRequest(method, url, nil)
+			for key, value := range header {
+				req.Header.Set(key, value)
+			}
+			mux.ServeHTTP(res, req)
+			return res
+		}
+		handler := func(name string) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, params Params) {
+				w.WriteHeader(200)
+				w.Write([]byte(name))
+			}
+		}
+
+		mux := New()
+		mux.Get("/api/user", handler("v2")).Header("Accept-Version", "2")
+		mux.Get("/api/user", handler("v3")).Header("Accept-Version", "3").Name("user-v3")
+		mux.Get("/api/user", handler("debug")).Query("debug", "")
+		mux.Get("/api/user", handler("v1"))
+		mux.Post("/api/user", handler("json")).ContentType("application/json")
+		mux.Post("/api/user", handler("form")).ContentType("application/x-www-form-urlencoded")
+		mux.Get("/api/report", handler("csv")).Accept("text/csv")
+		mux.Get("/api/report", handler("json")).Accept("application/json").MatchFunc(func(req *http.Request) bool {
+			return req.URL.Query().Get("format") != "none"
+		})
+
+		assert.Equal("v2", serve(mux, "GET", "/api/user", map[string]string{"Accept-Version": "2"}).Body.String())
+		assert.Equal("v3", serve(mux, "GET", "/api/user", map[string]string{"Accept-Version": "3"}).Body.String())
+		assert.Equal("debug", serve(mux, "GET", "/api/user?debug", nil).Body.String())
+		assert.Equal("v1", serve(mux, "GET", "/api/user", map[string]string{"Accept-Version": "4"}).Body.String())
+		assert.Equal("GET, POST", mux.Lookup("/api/user").GetAllow())
+		assert.Equal("user-v3", mux.Route("user-v3").GetName())
+
+		// the route without matchers is the last one
+		_, err := mux.HandleE("GET", "/api/user", handler("v4"))
+		assert.NotNil(err)
+		assert.Panics(func() {
+			mux.Post("/api/user", handler("any"))
+			mux.Post("/api/user", handler("other"))
+		})
+
+		assert.Equal("json", serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "application/json; charset=utf-8"}).Body.String())
+		assert.Equal("form", serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}).Body.String())
+
+		mux = New()
+		mux.Post("/api/user", handler("json")).ContentType("application/json")
+		mux.Get("/api/user", handler("v2")).Header("Accept-Version", "2")
+		mux.Get("/api/report", handler("csv")).Accept("text/csv")
+		mux.Get("/api/report", handler("json")).Accept("application/json").MatchFunc(func(req *http.Request) bool {
+			return req.URL.Query().Get("format") != "none"
+		})
+
+		assert.Equal(501, serve(mux, "GET", "/api/user", nil).Code)
+		assert.Equal(415, serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "text/plain"}).Code)
+		assert.Equal(415, serve(mux, "POST", "/api/user", nil).Code)
+		assert.Equal("csv", serve(mux, "GET", "/api/report", nil).Body.String())
+		assert.Equal("csv", serve(mux, "GET", "/api/report", map[string]string{"Accept": "text/*"}).Body.String())
+		assert.Equal("json", serve(mux, "GET", "/api/report", map[string]string{"Accept": "text/html;q=0.9, application/json"}).Body.String())
+		assert.Equal(406, serve(mux, "GET", "/api/report", map[string]string{"Accept": "text/html"}).Code)
+		assert.Equal(406, serve(mux, "GET", "/api/report?format=none", map[string]string{"Accept": "application/json"}).Code)
+		assert.Equal(405, serve(mux, "PUT", "/api/report", nil).Code)
+
+		// matchers added in Update don't change the serving table
+		assert.Nil(mux.Update(func(m *Mux) error {
+			m.Get("/api/report", handler("xml")).Accept("application/xml")
+			m.Post("/api/user", handler("text")).ContentType("text/plain")
+			assert.Equal(415, serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "text/plain"}).Code)
+			return nil
+		}))
+		assert.Equal("xml", serve(mux, "GET", "/api/report", map[string]string{"Accept": "application/xml"}).Body.String())
+		assert.Equal("text", serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "text/plain"}).Body.String())
+		assert.Equal("json", serve(mux, "POST", "/api/user", map[string]string{"Content-Type": "application/json"}).Body.String())
+	})
+
+	t.Run("Mux with EscapedPath option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		serve := func(mux *Mux, method, url string) *httptest.ResponseRecorder {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest(method, url, nil))
+			return res
+		}
+		handler := func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(200)
+			w.Write([]byte(params["name"] + params["filepath"]))
+		}
+
+		mux := New(trie.Options{EscapedPath: true, FixedPathRedirect: true, TrailingSlashRedirect: true})
+		mux.Get("/files/:name", handler)
+		mux.Get("/files/:name/raw", handler)
+		mux.Get("/static/:filepath*", handler)
+
+		assert.Equal("a/b", serve(mux, "GET", "/files/a%2Fb").Body.String())
+		assert.Equal("a b/c", serve(mux, "GET", "/files/a%20b%2Fc/raw").Body.String())
+		assert.Equal("a/b/c", serve(mux, "GET", "/static/a%2Fb/c").Body.String())
+		assert.Equal(501, serve(mux, "GET", "/files/a/b").Code)
+
+		res := serve(mux, "GET", "/files/a%2Fb/")
+		assert.Equal(301, res.Code)
+		assert.Equal("/files/a%2Fb", res.Header().Get("Location"))
+		res = serve(mux, "GET", "//files/a%2Fb/raw?x=1")
+		assert.Equal(301, res.Code)
+		assert.Equal("/files/a%2Fb/raw?x=1", res.Header().Get("Location"))
+
+		mux.RedirectPolicy(RedirectPolicy{Serve: true})
+		assert.Equal("a/b", serve(mux, "GET", "/files/a%2Fb/").Body.String())
+
+		// redirects in a mounted Mux
+		router := New()
+		router.Mount("/api v1", mux)
+		mux.RedirectPolicy(RedirectPolicy{})
+		res = serve(router, "GET", "/api%20v1/files/a%2Fb/")
+		assert.Equal(301, res.Code)
+		assert.Equal("/api%20v1/files/a%2Fb", res.Header().Get("Location"))
+
+		// the prefix param may contain encoded slashes
+		router = New(trie.Options{EscapedPath: true})
+		router.Mount("/buckets/:rest", mux)
+		res = serve(router, "GET", "/buckets/x%2Fy/files/a%2Fb")
+		assert.Equal(200, res.Code)
+		assert.Equal("a/b", res.Body.String())
+	})
+
+	t.Run("Mux.Update and Mux.Swap", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(body string) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, params Params) {
+				w.WriteHeader(200)
+				w.Write([]byte(body))
+			}
+		}
+		mux := New()
+		mux.Get("/a", handler("a")).Name("a")
+		mux.Get("/b", handler("b"))
+		route := mux.Route("a")
+
+		ts := httptest.NewServer(mux)
+		defer ts.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 50; i++ {
+				res, err := http.Get(ts.URL + "/a")
+				assert.Nil(err)
+				assert.Equal(200, res.StatusCode)
+				res.Body.Close()
+			}
+		}()
+
+		for i := 0; i < 50; i++ {
+			err := mux.Update(func(m *Mux) error {
+				m.Lookup("/b").Unhandle("GET")
+				m.Get("/b", handler(fmt.Sprint(i)))
+				return nil
+			})
+			assert.Nil(err)
+		}
+		<-done
+
+		res, err := http.Get(ts.URL + "/b")
+		assert.Nil(err)
+		body, _ := ioutil.ReadAll(res.Body)
+		assert.Equal("49", string(body))
+		res.Body.Close()
+
+		assert.NotNil(mux.Route("a"))
+		NotEqualPtr(t, route, mux.Route("a"))
+		EqualPtr(t, mux.Lookup("/a"), mux.Route("a").Node())
+
+		err = mux.Update(func(m *Mux) error {
+			m.Get("/c", handler("c"))
+			return errors.New("some error")
+		})
+		assert.Equal("some error", err.Error())
+		assert.Nil(mux.Lookup("/c"))
+
+		next := New()
+		next.Get("/c", handler("c")).Name("c")
+		mux.Swap(next)
+		assert.Nil(mux.Lookup("/a"))
+		assert.Nil(mux.Route("a"))
+		assert.NotNil(mux.Route("c"))
+
+		res, err = http.Get(ts.URL + "/c")
+		assert.Nil(err)
+		body, _ = ioutil.ReadAll(res.Body)
+		assert.Equal("c", string(body))
+		res.Body.Close()
+
+		// the routes of next run with the middleware of next
+		auth := func(next HandlerFunc) HandlerFunc {
+			return func(w http.ResponseWriter, req *http.Request, params Params) {
+				if req.Header.Get("Authorization") == "" {
+					w.WriteHeader(401)
+					return
+				}
+				next(w, req, params)
+			}
+		}
+		router := New()
+		router.Use(auth)
+		router.Get("/c", handler("c"))
+		serve := func(path string) int {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+			return res.Code
+		}
+		assert.Equal(401, serve("/c"))
+		router.Swap(next)
+		assert.Equal(200, serve("/c"))
+		assert.Equal(401, serve("/none"))
+		next = New()
+		next.Use(auth)
+		next.Get("/c", handler("c"))
+		router.Swap(next)
+		assert.Equal(401, serve("/c"))
+
+		// the names of removed routes are dropped
+		mux = New()
+		mux.Get("/", handler("root")).Name("root")
+		mux.Get("/legacy", handler("legacy")).Name("legacy")
+		mux.Get("/other", handler("other")).Name("other")
+		assert.Nil(mux.Update(func(m *Mux) error {
+			assert.True(m.Lookup("/legacy").Unhandle("GET"))
+			assert.Nil(m.Route("legacy"))
+			m.load().trie.Remove("/other")
+			return nil
+		}))
+		assert.Nil(mux.Route("legacy"))
+		_, err = mux.URL("legacy", nil)
+		assert.True(errors.Is(err, ErrRouteNotFound))
+		assert.Nil(mux.Route("other"))
+		assert.Nil(mux.Update(func(m *Mux) error {
+			assert.Nil(m.Route("legacy"))
+			m.Get("/legacy/v2", handler("legacy")).Name("legacy")
+			return nil
+		}))
+		url, err := mux.URL("legacy", nil)
+		assert.Nil(err)
+		assert.Equal("/legacy/v2", url)
+		assert.Equal("/", mux.Route("root").GetPattern())
+		assert.Nil(mux.Lookup(""))
+
+		// without Update
+		mux.Lookup("/legacy/v2").Unhandle("GET")
+		assert.Nil(mux.Route("legacy"))
+		mux.Get("/legacy/v3", handler("legacy")).Name("legacy")
+		assert.Equal("/legacy/v3", mux.Route("legacy").GetPattern())
+	})
+
+	t.Run("Mux.ExplainHandler", func(t *testing.T) {
+		assert := assert.New(t)
+
+		mux := New()
+		mux.Get(`/api/:type/:ID(^\d+$)`, func(w http.ResponseWriter, req *http.Request, params Params) {
+			w.WriteHeader(204)
+		})
+		mux.Handler("GET", "/debug/routes", mux.ExplainHandler())
+
+		ts := httptest.NewServer(mux)
+		defer ts.Close()
+
+		res, err := http.Get(ts.URL + "/debug/routes?path=/api/user/123")
+		assert.Nil(err)
+		assert.Equal(200, res.StatusCode)
+		assert.Equal("application/json; charset=utf-8", res.Header.Get("Content-Type"))
+		trace := new(trie.Trace)
+		assert.Nil(json.NewDecoder(res.Body).Decode(trace))
+		res.Body.Close()
+		assert.Equal(`/api/:type/:ID(^\d+$)`, trace.Pattern)
+		assert.Equal("GET", trace.Allow)
+
+		res, err = http.Get(ts.URL + "/debug/routes?format=text&path=/api/user/abc")
+		assert.Nil(err)
+		assert.Equal(200, res.StatusCode)
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.Contains(string(body), "param: /api/:type/:ID(^\\d+$), regexp not matched\n")
+		assert.Contains(string(body), "result: not matched\n")
+
+		res, err = http.Get(ts.URL + "/debug/routes")
+		assert.Nil(err)
+		assert.Equal(400, res.StatusCode)
+		res.Body.Close()
+	})
+
 	t.Run("Mux.Handler", func(t *testing.T) {
 		assert := assert.New(t)
 
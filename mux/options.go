@@ -0,0 +1,143 @@
+package mux
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptionsFunc is a handler for the OPTIONS requests on a matched path without
+// OPTIONS handler, allow is the value of the Allow header, e.g. "GET, POST".
+type OptionsFunc func(w http.ResponseWriter, req *http.Request, allow string)
+
+// AutoOptions registers a handler in the Mux to respond to the OPTIONS
+// requests on a matched path without OPTIONS handler, instead of responding
+// 204 with the Allow header. The Allow header is set before it runs.
+//
+//  cors := mux.CORS{AllowOrigins: []string{"https://example.com"}, MaxAge: time.Hour}
+//  router.AutoOptions(cors.Respond)
+//  router.Use(cors.Middleware)
+//
+func (m *Mux) AutoOptions(handler OptionsFunc) {
+	m.configure()
+	m.autoOptions = handler
+	m.compose()
+}
+
+// GlobalOptions registers a handler in the Mux for the "OPTIONS *" requests.
+// The Allow header is set to all methods registered in the Mux before it runs,
+// by default they are responded 204. Note that http.Server responds to them
+// itself unless DisableGeneralOptionsHandler is set.
+func (m *Mux) GlobalOptions(handler HandlerFunc) {
+	m.configure()
+	m.globalOptions = handler
+	m.compose()
+}
+
+// optionsHandler returns the handler for the OPTIONS requests.
+func (s *settings) optionsHandler() HandlerFunc {
+	if s.autoOptions != nil {
+		handler := s.autoOptions
+		return func(w http.ResponseWriter, req *http.Request, _ Params) {
+			handler(w, req, w.Header().Get("Allow"))
+		}
+	}
+	return respondOptions
+}
+
+// globalOptionsHandler returns the handler for the "OPTIONS *" requests.
+func (s *settings) globalOptionsHandler() HandlerFunc {
+	if s.globalOptions != nil {
+		return s.globalOptions
+	}
+	return respondOptions
+}
+
+// getMethods returns all methods registered in the Mux, sorted.
+func (m *Mux) getMethods() string {
+	set := make(map[string]bool)
+	for _, node := range m.GetEndpoints() {
+		if allow := m.getAllow(node); allow != "" {
+			for _, method := range strings.Split(allow, ", ") {
+				set[method] = true
+			}
+		}
+	}
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// CORS is a policy of Cross-Origin Resource Sharing. Use CORS.Respond to
+// respond to preflight requests with the methods of the matched route, and
+// CORS.Middleware to add the CORS headers to other requests.
+type CORS struct {
+	// The allowed origins, e.g. "https://example.com", or "*" for any origin.
+	AllowOrigins []string
+	// The request headers allowed in the actual request.
+	AllowHeaders []string
+	// The response headers exposed to the client.
+	ExposeHeaders []string
+	// Whether credentials are allowed.
+	AllowCredentials bool
+	// How long the results of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// Respond responds to an OPTIONS request, it is an OptionsFunc for
+// Mux.AutoOptions. For a preflight request from an allowed origin, allow is
+// responded in the Access-Control-Allow-Methods header.
+func (c CORS) Respond(w http.ResponseWriter, req *http.Request, allow string) {
+	if req.Header.Get("Access-Control-Request-Method") != "" && c.setOrigin(w, req) {
+		header := w.Header()
+		header.Set("Access-Control-Allow-Methods", allow)
+		if len(c.AllowHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(c.AllowHeaders, ", "))
+		}
+		if c.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+		}
+	}
+	w.WriteHeader(204)
+}
+
+// Middleware adds the CORS headers to the response of a request from an
+// allowed origin.
+func (c CORS) Middleware(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, params Params) {
+		if req.Method != http.MethodOptions && c.setOrigin(w, req) && len(c.ExposeHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposeHeaders, ", "))
+		}
+		next(w, req, params)
+	}
+}
+
+// setOrigin sets the allowed origin headers, it returns false if the origin
+// of the request is not allowed.
+func (c CORS) setOrigin(w http.ResponseWriter, req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	for _, o := range c.AllowOrigins {
+		if o == "*" && !c.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+			return true
+		}
+		if o == "*" || o == origin {
+			header.Set("Access-Control-Allow-Origin", origin)
+			if c.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			return true
+		}
+	}
+	return false
+}
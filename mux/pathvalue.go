@@ -0,0 +1,9 @@
+//go:build !go1.22
+// +build !go1.22
+
+package mux
+
+import "net/http"
+
+// setPathValues is a no-op, Request.SetPathValue is added in Go 1.22.
+func setPathValues(req *http.Request, params Params) {}
@@ -0,0 +1,12 @@
+//go:build go1.22
+// +build go1.22
+
+package mux
+
+import "net/http"
+
+func setPathValues(req *http.Request, params Params) {
+	for name, value := range params {
+		req.SetPathValue(name, value)
+	}
+}
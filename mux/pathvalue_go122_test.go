@@ -0,0 +1,28 @@
+//go:build go1.22
+// +build go1.22
+
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMuxPathValue(t *testing.T) {
+	t.Run("Request.PathValue", func(t *testing.T) {
+		assert := assert.New(t)
+
+		mux := New()
+		mux.HandlerFunc("GET", "/api/:type/:ID", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(200)
+			w.Write([]byte(req.PathValue("type") + " " + req.PathValue("ID")))
+		})
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, httptest.NewRequest("GET", "/api/task/123", nil))
+		assert.Equal("task 123", res.Body.String())
+	})
+}
@@ -0,0 +1,106 @@
+package mux
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/teambition/trie-mux"
+)
+
+// RedirectPolicy configures the fixed path and trailing slash redirects of a
+// Mux, see trie.Options. A zero status code uses the default one. The case
+// insensitive redirects use the status codes of fixed path redirects.
+type RedirectPolicy struct {
+	// The status code of trailing slash redirects for GET and HEAD requests,
+	// default 301.
+	TrailingSlash int
+	// The status code of trailing slash redirects for other methods, default 307.
+	TrailingSlashUnsafe int
+	// The status code of fixed path redirects for GET and HEAD requests,
+	// default 301.
+	FixedPath int
+	// The status code of fixed path redirects for other methods, default 307.
+	FixedPathUnsafe int
+	// If enabled, the route of the redirect location is served directly
+	// without a round trip.
+	Serve bool
+	// Veto is called before redirecting, if it returns true the request is
+	// handled as not found instead.
+	Veto func(req *http.Request, url string) bool
+}
+
+// RedirectPolicy sets the redirect policy of the Mux.
+//
+//  mux.RedirectPolicy(mux.RedirectPolicy{
+//  	FixedPath: 308,
+//  	Veto: func(req *http.Request, url string) bool {
+//  		return strings.HasPrefix(req.URL.Path, "/api/")
+//  	},
+//  })
+//
+func (m *Mux) RedirectPolicy(policy RedirectPolicy) {
+	m.configure()
+	m.redirectPolicy = policy
+}
+
+func (p *RedirectPolicy) code(method string, fixedPath bool) int {
+	safe := method == http.MethodGet || method == http.MethodHead
+	code := 0
+	switch {
+	case fixedPath && safe:
+		code = p.FixedPath
+	case fixedPath:
+		code = p.FixedPathUnsafe
+	case safe:
+		code = p.TrailingSlash
+	default:
+		code = p.TrailingSlashUnsafe
+	}
+	switch {
+	case code != 0:
+		return code
+	case safe:
+		return 301
+	}
+	return 307
+}
+
+// redirectTo redirects the request to the path, or serves the route of the
+// path directly. It returns false if the redirect is vetoed, or the path to
+// serve directly is not matched.
+func (m *Mux) redirectTo(w http.ResponseWriter, req *http.Request, host trie.Params, path string, fixedPath bool) bool {
+	t := m.load()
+	u := *req.URL
+	u.Path, u.RawPath = path, ""
+	if t.escaped {
+		// the path is escaped, see trie.Options.EscapedPath
+		u.Path, _ = url.PathUnescape(path)
+		u.RawPath = path
+	}
+	if m.redirectPolicy.Serve {
+		// serve the route of the path once, it is not redirected again
+		res := trie.AcquireMatched()
+		defer trie.ReleaseMatched(res)
+		if t.trie.MatchInto(path, res); res.Node == nil {
+			return false
+		}
+		r := req.WithContext(req.Context())
+		r.URL = &u
+		m.serveNode(w, r, t, host, res)
+		return true
+	}
+
+	// redirect to the path with the prefix stripped by Mount
+	if prefix := PrefixFromContext(req.Context()); prefix != "" {
+		u.Path = prefix + u.Path
+		if u.RawPath != "" {
+			u.RawPath = (&url.URL{Path: prefix}).EscapedPath() + u.RawPath
+		}
+	}
+	location := u.String()
+	if veto := m.redirectPolicy.Veto; veto != nil && veto(req, location) {
+		return false
+	}
+	m.redirectHandler()(w, req, location, m.redirectPolicy.code(req.Method, fixedPath))
+	return true
+}
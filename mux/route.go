@@ -0,0 +1,81 @@
+package mux
+
+import (
+	"fmt"
+
+	"github.com/teambition/trie-mux"
+)
+
+// Route is a handler registered with a method on a pattern in the Mux.
+type Route struct {
+	table  *table
+	node   *trie.Node
+	method string
+	name   string
+	// the handler and matchers of the route
+	variant *variant
+}
+
+// Name sets a name for the route, so that it can be referred by Mux.Route and
+// Mux.URL. It panics if the name is already used by another route.
+//
+//  mux.Get("/view/:view", handler).Name("view")
+//  mux.URL("view", mux.Params{"view": "users"}) // "/view/users", nil
+//
+func (r *Route) Name(name string) *Route {
+	if route, ok := r.table.routes[name]; ok && route != r && route.handled() {
+		panic(fmt.Errorf(`route name "%s" already defined`, name))
+	}
+	if r.name != "" {
+		delete(r.table.routes, r.name)
+	}
+	r.name = name
+	r.table.routes[name] = r
+	return r
+}
+
+// GetName returns the name of the route, or an empty string.
+func (r *Route) GetName() string {
+	return r.name
+}
+
+// GetMethod returns the method of the route.
+func (r *Route) GetMethod() string {
+	return r.method
+}
+
+// GetPattern returns the pattern of the route.
+func (r *Route) GetPattern() string {
+	return r.node.GetPattern()
+}
+
+// Meta attaches metadata to the route, such as an *openapi.Operation with
+// the summary of the route for API documents.
+//
+//  mux.Get("/api/task/:ID", handler).Meta(&openapi.Operation{Summary: "Returns the task"})
+//
+func (r *Route) Meta(meta interface{}) *Route {
+	r.node.SetMeta(r.method, meta)
+	return r
+}
+
+// GetMeta returns the metadata of the route, or nil.
+func (r *Route) GetMeta() interface{} {
+	return r.node.GetMeta(r.method)
+}
+
+// Node returns the trie node of the route.
+func (r *Route) Node() *trie.Node {
+	return r.node
+}
+
+// URL builds a URL path for the route with the parameter values.
+func (r *Route) URL(params Params) (string, error) {
+	return r.node.URL(params)
+}
+
+// handled reports whether the handler of the route is still registered, it is
+// false after the handler is removed by Node.Unhandle or Trie.Remove.
+func (r *Route) handled() bool {
+	return r.node.GetHandler(r.method) != nil
+}
@@ -0,0 +1,146 @@
+package mux
+
+import (
+	"strings"
+
+	"github.com/teambition/trie-mux"
+)
+
+// table is a routing table of a Mux. A serving table is never modified,
+// Mux.Update and Mux.Swap replace it atomically.
+type table struct {
+	trie   *trie.Trie
+	routes map[string]*Route
+	// the host Muxes by reversed host pattern, see Mux.Host
+	hosts *trie.Trie
+	// whether the trie matches the escaped path, see trie.Options.EscapedPath
+	escaped bool
+	// the handlers of the nodes registered by Mux.Mount, for the requests with
+	// methods not registered
+	mounts map[*trie.Node]HandlerFunc
+}
+
+func newTable(tr *trie.Trie) *table {
+	return &table{trie: tr, routes: make(map[string]*Route), escaped: tr.GetOptions().EscapedPath,
+		mounts: make(map[*trie.Node]HandlerFunc)}
+}
+
+func (t *table) clone() *table {
+	c := newTable(t.trie.Clone())
+	// the variants of routes with matchers are copied, so that matchers added
+	// to the copy don't change the serving table
+	copies := make(map[*variant]*variant)
+	for _, node := range c.trie.GetEndpoints() {
+		for _, method := range strings.Split(node.GetAllow(), ", ") {
+			if vs, ok := node.GetHandler(method).(variants); ok {
+				node.Replace(method, vs.clone(copies))
+			}
+		}
+	}
+	for name, route := range t.routes {
+		// skip the routes removed from the trie
+		if !route.handled() {
+			continue
+		}
+		node := c.trie.Lookup(route.GetPattern())
+		if node == nil || node.GetHandler(route.method) == nil {
+			continue
+		}
+		v, ok := copies[route.variant]
+		if !ok {
+			v = &variant{handler: route.variant.handler}
+		}
+		c.routes[name] = &Route{table: c, node: node, method: route.method, name: name, variant: v}
+	}
+	for node, handler := range t.mounts {
+		if n := c.trie.Lookup(node.GetPattern()); n != nil {
+			c.mounts[n] = handler
+		}
+	}
+	if t.hosts != nil {
+		c.hosts = t.hosts.Clone()
+		for _, node := range c.hosts.GetEndpoints() {
+			h := node.GetHandler(hostKey).(*Mux)
+			next := &Mux{settings: h.settings}
+			next.table.Store(h.load().clone())
+			node.Replace(hostKey, next)
+		}
+	}
+	return c
+}
+
+// share makes the host Muxes of the table share the settings, it is called
+// before the table is stored.
+func (t *table) share(s *settings) {
+	if t.hosts == nil {
+		return
+	}
+	for _, node := range t.hosts.GetEndpoints() {
+		node.GetHandler(hostKey).(*Mux).settings = s
+	}
+}
+
+func (m *Mux) load() *table {
+	return m.table.Load().(*table)
+}
+
+// Update applies fn to a copy of the routing table of the Mux, then swaps the
+// copy in atomically. Requests being served are not affected by fn, they
+// finish against the previous table. If fn returns an error, the copy is
+// discarded and the error is returned. Only the routes can be changed by fn,
+// the settings methods of the Mux passed to fn, such as Use and NotFound,
+// panic.
+//
+//  err := mux.Update(func(m *mux.Mux) error {
+//  	m.Get("/api/feature", handler)
+//  	m.Lookup("/api/legacy").Unhandle("GET")
+//  	return nil
+//  })
+//
+// Concurrent calls of Update and Swap are serialized.
+func (m *Mux) Update(fn func(*Mux) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := *m.settings
+	s.updating = true
+	next := &Mux{settings: &s}
+	next.table.Store(m.load().clone())
+	next.load().share(&s)
+	if err := fn(next); err != nil {
+		return err
+	}
+	t := next.load()
+	for name, route := range t.routes {
+		if !route.handled() {
+			delete(t.routes, name)
+		}
+	}
+	t.share(m.settings)
+	m.table.Store(t)
+	return nil
+}
+
+// Swap replaces the routing table of the Mux with the one of next atomically,
+// it is useful to reload all routes from configuration. Settings of the Mux,
+// such as Otherwise, are kept and shared with the host Muxes of next. The next
+// Mux should not be modified after swapping.
+//
+// Middleware wrap the routes when they are registered, so the routes of next
+// run with the middleware of next, not the ones of the Mux, while the not found
+// and method not allowed responses still run with the middleware of the Mux.
+// Middleware the routes rely on, such as an auth layer, must be used on next:
+//
+//  next := mux.New()
+//  next.Use(auth)
+//  next.Get("/api/feature", handler)
+//  router.Swap(next)
+//
+func (m *Mux) Swap(next *Mux) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := next.load()
+	t.share(m.settings)
+	m.table.Store(t)
+}
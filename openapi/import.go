@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/teambition/trie-mux/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// Unmapped is an operation of a document that is not registered on a Mux,
+// because it has no operationId or no handler is resolved for it.
+type Unmapped struct {
+	Method, Path, OperationID string
+}
+
+func (u Unmapped) String() string {
+	if u.OperationID == "" {
+		return fmt.Sprintf("%s %s: no operationId", u.Method, u.Path)
+	}
+	return fmt.Sprintf(`%s %s: no handler for "%s"`, u.Method, u.Path, u.OperationID)
+}
+
+// Load parses an OpenAPI 3 document in JSON or YAML. Fields not described by
+// Document are ignored.
+func Load(data []byte) (*Document, error) {
+	doc := new(Document)
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	// YAML keys can be non-string, e.g. the status codes of responses
+	data, err := json.Marshal(stringKeys(v))
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// LoadFile reads and parses an OpenAPI 3 document file in JSON or YAML.
+func LoadFile(filename string) (*Document, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// Register registers the operations of the document on the Mux. The resolve
+// function returns the handler of an operation by its operationId, or nil if
+// not implemented. Registered routes are named by operationId and have the
+// operation attached as metadata. It returns the operations not registered,
+// sorted by path and method, or an error if a path can't be translated to a
+// pattern or the pattern can't be defined.
+//
+//  doc, err := openapi.LoadFile("api.yaml")
+//  unmapped, err := openapi.Register(router, doc, func(operationID string) mux.HandlerFunc {
+//  	return handlers[operationID]
+//  })
+//  for _, u := range unmapped {
+//  	log.Println(u) // GET /api/tasks/{ID}: no handler for "getTask"
+//  }
+//
+func Register(m *mux.Mux, doc *Document, resolve func(operationID string) mux.HandlerFunc) ([]Unmapped, error) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	unmapped := make([]Unmapped, 0)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		ops := item.Operations()
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			op := ops[method]
+			var handler mux.HandlerFunc
+			if op.OperationID != "" {
+				handler = resolve(op.OperationID)
+			}
+			if handler == nil {
+				unmapped = append(unmapped, Unmapped{Method: method, Path: path, OperationID: op.OperationID})
+				continue
+			}
+
+			// parameters of the operation override the ones of the path item
+			params := append(append([]Parameter{}, item.Parameters...), op.Parameters...)
+			pattern, err := Pattern(path, params)
+			if err != nil {
+				return nil, err
+			}
+			route, err := m.HandleE(method, pattern, handler)
+			if err != nil {
+				return nil, err
+			}
+			route.Meta(op)
+			if m.Route(op.OperationID) == nil {
+				route.Name(op.OperationID)
+			}
+		}
+	}
+	return unmapped, nil
+}
+
+// Pattern translates an OpenAPI path template to a trie pattern. The schemas
+// of path parameters become typed constraints if they are ones of the builtin
+// constraints of trie, e.g. "integer" becomes "<int>", other schema patterns
+// become regexp constraints, a literal after a
+// parameter becomes a suffix, and "{name=**}" becomes a catch-all parameter.
+//
+//  openapi.Pattern("/api/{type}/{ID}:cancel", nil) // "/api/:type/:ID+:cancel", nil
+//  openapi.Pattern("/files/{path=**}", nil)        // "/files/:path*", nil
+//
+func Pattern(path string, params []Parameter) (string, error) {
+	constraints := make(map[string]string)
+	for _, param := range params {
+		if param.In == "path" && param.Schema != nil {
+			constraints[param.Name] = constraint(param.Schema)
+		}
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		start := strings.IndexByte(segment, '{')
+		switch {
+		case start == -1 && strings.HasPrefix(segment, ":"):
+			segments[i] = ":" + segment
+			continue
+		case start == -1:
+			continue
+		case start > 0:
+			return "", fmt.Errorf(`unsupported path template "%s": literal before parameter`, path)
+		}
+
+		end := strings.IndexByte(segment, '}')
+		if end == -1 {
+			return "", fmt.Errorf(`invalid path template "%s"`, path)
+		}
+		name, suffix := segment[1:end], segment[end+1:]
+		if strings.IndexByte(suffix, '{') > -1 {
+			return "", fmt.Errorf(`unsupported path template "%s": multiple parameters in a segment`, path)
+		}
+
+		switch {
+		case strings.HasSuffix(name, "=**"):
+			if suffix != "" {
+				return "", fmt.Errorf(`unsupported path template "%s": literal after catch-all parameter`, path)
+			}
+			segments[i] = ":" + name[:len(name)-3] + "*"
+			continue
+		case strings.HasSuffix(name, "=*"):
+			name = name[:len(name)-2]
+		}
+
+		pattern := ":" + name + constraints[name]
+		if suffix != "" {
+			pattern += "+" + suffix
+		}
+		segments[i] = pattern
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// constraint returns the constraint of a path parameter by its schema, e.g.
+// "<uuid>" or "(^[a-f0-9]+$)", or an empty string.
+func constraint(schema *Schema) string {
+	name := ""
+	for n, s := range constraintSchemas {
+		switch {
+		case s.Pattern != "" && s.Pattern == schema.Pattern:
+			return "<" + n + ">"
+		case s.Pattern == "" && s.Type == schema.Type && s.Format == schema.Format,
+			schema.Type == "integer" && n == "int":
+			name = n
+		}
+	}
+	switch {
+	case name != "" && schema.Pattern != "":
+		return "<" + name + ">(" + schema.Pattern + ")"
+	case name != "":
+		return "<" + name + ">"
+	case schema.Pattern != "":
+		return "(" + schema.Pattern + ")"
+	}
+	return ""
+}
+
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teambition/trie-mux"
+	"github.com/teambition/trie-mux/mux"
+)
+
+const spec = `
+openapi: 3.0.3
+info:
+  title: Tasks
+  version: 1.0.0
+paths:
+  /api/tasks/{ID}:
+    parameters:
+      - name: ID
+        in: path
+        required: true
+        schema:
+          type: string
+          pattern: ^\d+$
+    get:
+      operationId: getTask
+      responses:
+        200:
+          description: The task
+    delete:
+      operationId: deleteTask
+      responses:
+        204:
+          description: Deleted
+  /api/tasks/{ID}:cancel:
+    post:
+      operationId: cancelTask
+      responses:
+        default:
+          description: Default response
+  /files/{path=**}:
+    get:
+      operationId: getFile
+      responses:
+        default:
+          description: Default response
+  /health:
+    get:
+      responses:
+        default:
+          description: Default response
+`
+
+func TestOpenAPIImport(t *testing.T) {
+	t.Run("Pattern", func(t *testing.T) {
+		assert := assert.New(t)
+
+		for path, pattern := range map[string]string{
+			"/":                       "/",
+			"/api/{type}/{id}":        "/api/:type/:id",
+			"/api/{type}/{ID}:cancel": `/api/:type/:ID(^\d+$)+:cancel`,
+			"/api/{name=*}/:batch":    "/api/:name/::batch",
+			"/files/{path=**}":        "/files/:path*",
+			`/api/{ID}`:               `/api/:ID(^\d+$)`,
+		} {
+			p, err := Pattern(path, []Parameter{{Name: "ID", In: "path", Schema: &Schema{Pattern: `^\d+$`}}})
+			assert.Nil(err)
+			assert.Equal(pattern, p)
+		}
+
+		// typed constraints
+		p, err := Pattern("/posts/{ID}/{uuid}/{date}/{slug}/{code}.json", []Parameter{
+			{Name: "ID", In: "path", Schema: &Schema{Type: "integer", Format: "int32"}},
+			{Name: "uuid", In: "path", Schema: &Schema{Type: "string", Format: "uuid"}},
+			{Name: "date", In: "path", Schema: &Schema{Type: "string", Format: "date"}},
+			{Name: "slug", In: "path", Schema: &Schema{Type: "string", Pattern: `^[a-z0-9]+(?:-[a-z0-9]+)*$`}},
+			{Name: "code", In: "path", Schema: &Schema{Type: "integer", Pattern: `^\d{3}$`}},
+		})
+		assert.Nil(err)
+		assert.Equal(`/posts/:ID<int>/:uuid<uuid>/:date<date>/:slug<slug>/:code<int>(^\d{3}$)+.json`, p)
+		tr := trie.New()
+		path, params := Path(tr.Define(p))
+		p2, err := Pattern(path, params)
+		assert.Nil(err)
+		assert.Equal(p, p2)
+
+		_, err = Pattern("/api/v{version}", nil)
+		assert.Equal(`unsupported path template "/api/v{version}": literal before parameter`, err.Error())
+		_, err = Pattern("/api/{a}.{b}", nil)
+		assert.Equal(`unsupported path template "/api/{a}.{b}": multiple parameters in a segment`, err.Error())
+		_, err = Pattern("/files/{path=**}.json", nil)
+		assert.Equal(`unsupported path template "/files/{path=**}.json": literal after catch-all parameter`, err.Error())
+		_, err = Pattern("/api/{ID", nil)
+		assert.Equal(`invalid path template "/api/{ID"`, err.Error())
+	})
+
+	t.Run("Load and Register", func(t *testing.T) {
+		assert := assert.New(t)
+
+		doc, err := Load([]byte(spec))
+		assert.Nil(err)
+		assert.Equal("Tasks", doc.Info.Title)
+		assert.Equal("The task", doc.Paths["/api/tasks/{ID}"].Get.Responses["200"].Description)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params mux.Params) {
+			w.Header().Set("x-params", params["ID"]+params["path"])
+			w.WriteHeader(200)
+		}
+		handlers := map[string]mux.HandlerFunc{
+			"getTask":    handler,
+			"cancelTask": handler,
+			"getFile":    handler,
+		}
+		m := mux.New()
+		unmapped, err := Register(m, doc, func(operationID string) mux.HandlerFunc {
+			return handlers[operationID]
+		})
+		assert.Nil(err)
+		assert.Equal([]Unmapped{
+			{Method: "DELETE", Path: "/api/tasks/{ID}", OperationID: "deleteTask"},
+			{Method: "GET", Path: "/health"},
+		}, unmapped)
+		assert.Equal(`DELETE /api/tasks/{ID}: no handler for "deleteTask"`, unmapped[0].String())
+		assert.Equal("GET /health: no operationId", unmapped[1].String())
+
+		route := m.Route("getTask")
+		assert.Equal(`/api/tasks/:ID(^\d+$)`, route.GetPattern())
+		assert.Equal("getTask", route.GetMeta().(*Operation).OperationID)
+		url, err := m.URL("cancelTask", mux.Params{"ID": "123"})
+		assert.Nil(err)
+		assert.Equal("/api/tasks/123:cancel", url)
+
+		for path, value := range map[string]string{
+			"/api/tasks/123":        "123",
+			"/api/tasks/123:cancel": "",
+			"/files/a/b.txt":        "a/b.txt",
+		} {
+			method := "GET"
+			if value == "" {
+				method, value = "POST", "123"
+			}
+			res := httptest.NewRecorder()
+			m.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+			assert.Equal(200, res.Code)
+			assert.Equal(value, res.Header().Get("x-params"))
+		}
+		res := httptest.NewRecorder()
+		m.ServeHTTP(res, httptest.NewRequest("GET", "/api/tasks/abc", nil))
+		assert.Equal(501, res.Code)
+
+		paths := New(doc.Info, m.GetEndpoints()).Paths
+		assert.Equal(3, len(paths))
+		assert.Equal("getTask", paths["/api/tasks/{ID}"].Get.OperationID)
+		assert.Equal(`^\d+$`, paths["/api/tasks/{ID}"].Get.Parameters[0].Schema.Pattern)
+	})
+
+	t.Run("Load JSON", func(t *testing.T) {
+		assert := assert.New(t)
+
+		doc, err := Load([]byte(`{"openapi": "3.0.3", "paths": {"/a": {"get": {"operationId": "a"}}}}`))
+		assert.Nil(err)
+		assert.Equal("a", doc.Paths["/a"].Get.OperationID)
+
+		_, err = Load([]byte(`{"paths": []}`))
+		assert.NotNil(err)
+		_, err = Load([]byte("paths: [a"))
+		assert.NotNil(err)
+		_, err = LoadFile("not-exist.yaml")
+		assert.NotNil(err)
+	})
+
+	t.Run("Register errors", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params mux.Params) {}
+		resolve := func(operationID string) mux.HandlerFunc { return handler }
+		doc, _ := Load([]byte(`{"paths": {"/a/v{version}": {"get": {"operationId": "a"}}}}`))
+		_, err := Register(mux.New(), doc, resolve)
+		assert.Equal(`unsupported path template "/a/v{version}": literal before parameter`, err.Error())
+
+		m := mux.New()
+		m.Get("/a/:b", handler)
+		doc, _ = Load([]byte(`{"paths": {"/a/{c}": {"get": {"operationId": "a"}}}}`))
+		_, err = Register(m, doc, resolve)
+		assert.Equal(`invalid pattern name "c", as prev defined "/a/:b"`, err.Error())
+	})
+}
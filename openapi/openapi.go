@@ -0,0 +1,241 @@
+// Package openapi generates OpenAPI 3 documents from the routes of a trie or mux,
+// and registers the operations of OpenAPI 3 documents on a mux.
+package openapi
+
+import (
+	"strings"
+
+	"github.com/teambition/trie-mux"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI string `json:"openapi"`
+	Info    Info   `json:"info"`
+	Paths   Paths  `json:"paths"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Paths holds the path items by path template, e.g. "/api/{type}/{ID}:cancel".
+type Paths map[string]*PathItem
+
+// PathItem describes the operations available on a path.
+type PathItem struct {
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Get         *Operation  `json:"get,omitempty"`
+	Put         *Operation  `json:"put,omitempty"`
+	Post        *Operation  `json:"post,omitempty"`
+	Delete      *Operation  `json:"delete,omitempty"`
+	Options     *Operation  `json:"options,omitempty"`
+	Head        *Operation  `json:"head,omitempty"`
+	Patch       *Operation  `json:"patch,omitempty"`
+	Trace       *Operation  `json:"trace,omitempty"`
+	Parameters  []Parameter `json:"parameters,omitempty"`
+}
+
+// Operation describes an API operation on a path. It can be attached to a
+// route as metadata, see Node.SetMeta and Route.Meta.
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+}
+
+// Parameter describes a parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// Schema is the schema of a parameter.
+type Schema struct {
+	Type    string `json:"type,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// constraintSchemas are the schemas of the builtin constraints of trie, the
+// params with other constraints are strings.
+var constraintSchemas = map[string]Schema{
+	"int":  {Type: "integer", Format: "int64"},
+	"uuid": {Type: "string", Format: "uuid"},
+	"date": {Type: "string", Format: "date"},
+	"slug": {Type: "string", Pattern: `^[a-z0-9]+(?:-[a-z0-9]+)*$`},
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// Operations returns the operations of the path item by method.
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		"GET":     p.Get,
+		"PUT":     p.Put,
+		"POST":    p.Post,
+		"DELETE":  p.Delete,
+		"OPTIONS": p.Options,
+		"HEAD":    p.Head,
+		"PATCH":   p.Patch,
+		"TRACE":   p.Trace,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// SetOperation sets the operation for the method, it returns false if the
+// method is not supported by OpenAPI.
+func (p *PathItem) SetOperation(method string, op *Operation) bool {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	case "TRACE":
+		p.Trace = op
+	default:
+		return false
+	}
+	return true
+}
+
+// New generates an OpenAPI 3 document from the endpoints of a trie or mux.
+// Named parameters become path templates, regexp constraints become schema
+// patterns, typed constraints like "<int>" become schema types and formats,
+// suffixes become literal custom methods (e.g. "/api/{type}/{ID}:cancel"),
+// and catch-all parameters are documented path parameters which may contain "/".
+// The *Operation (or Operation) attached to a handler as metadata is used for
+// its operation. Methods not supported by OpenAPI are skipped.
+//
+//  doc := openapi.New(openapi.Info{Title: "API", Version: "1.0.0"}, mux.GetEndpoints())
+//  data, err := json.Marshal(doc)
+//
+func New(info Info, endpoints []*trie.Node) *Document {
+	doc := &Document{OpenAPI: Version, Info: info, Paths: make(Paths)}
+	for _, node := range endpoints {
+		path, params := Path(node)
+		item := doc.Paths[path]
+		if item == nil {
+			item = new(PathItem)
+		}
+		for _, method := range node.GetMethods() {
+			if item.SetOperation(method, newOperation(node.GetMeta(method), params)) {
+				doc.Paths[path] = item
+			}
+		}
+	}
+	return doc
+}
+
+// Path returns the OpenAPI path template of the node's pattern and the path
+// parameters of it.
+//
+//  node := trie.Define(`/api/:type/:ID(^\d+$)+:cancel`)
+//  openapi.Path(node) // "/api/{type}/{ID}:cancel", [type ID]
+//
+func Path(node *trie.Node) (string, []Parameter) {
+	pattern := node.GetPattern()
+	if i := strings.IndexByte(pattern, '?'); i > -1 {
+		pattern = pattern[:i]
+	}
+	params := node.GetParams()
+	parameters := make([]Parameter, 0, len(params))
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, "::"):
+			segments[i] = segment[1:]
+		case strings.HasPrefix(segment, ":"):
+			param := params[len(parameters)]
+			segments[i] = "{" + param.Name + "}" + param.Suffix
+			schema, ok := constraintSchemas[param.Constraint]
+			if !ok {
+				schema = Schema{Type: "string"}
+			}
+			if param.Regex != "" {
+				schema.Pattern = param.Regex
+			}
+			parameter := Parameter{Name: param.Name, In: "path", Required: true, Schema: &schema}
+			if param.Wildcard {
+				parameter.Description = `Catch-all parameter, the value may contain "/".`
+			}
+			parameters = append(parameters, parameter)
+		case strings.HasSuffix(segment, "*"):
+			segments[i] = segment[:len(segment)-1]
+		}
+	}
+	return "/" + strings.Join(segments, "/"), parameters
+}
+
+func newOperation(meta interface{}, params []Parameter) *Operation {
+	op := new(Operation)
+	switch v := meta.(type) {
+	case *Operation:
+		*op = *v
+	case Operation:
+		*op = v
+	}
+
+	// descriptions and schemas of path parameters can be defined in the metadata
+	parameters := make([]Parameter, 0, len(params)+len(op.Parameters))
+	for _, param := range params {
+		for _, p := range op.Parameters {
+			if p.In == param.In && p.Name == param.Name {
+				if p.Description != "" {
+					param.Description = p.Description
+				}
+				if p.Schema != nil {
+					param.Schema = p.Schema
+				}
+				break
+			}
+		}
+		parameters = append(parameters, param)
+	}
+	for _, p := range op.Parameters {
+		if p.In != "path" {
+			parameters = append(parameters, p)
+		}
+	}
+	op.Parameters = nil
+	if len(parameters) > 0 {
+		op.Parameters = parameters
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses = map[string]Response{"default": {Description: "Default response"}}
+	}
+	return op
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teambition/trie-mux"
+	"github.com/teambition/trie-mux/mux"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("Path", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := trie.New()
+		path, params := Path(tr.Define("/"))
+		assert.Equal("/", path)
+		assert.Equal(0, len(params))
+
+		path, params = Path(tr.Define(`/api/:type/:ID(^\d+$)+:cancel`))
+		assert.Equal("/api/{type}/{ID}:cancel", path)
+		assert.Equal([]Parameter{
+			{Name: "type", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "ID", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: `^\d+$`}},
+		}, params)
+
+		path, _ = Path(tr.Define("/a/c*"))
+		assert.Equal("/a/c", path)
+
+		path, params = Path(tr.Define("/a/::b/:rest*"))
+		assert.Equal("/a/:b/{rest}", path)
+		assert.Equal(1, len(params))
+		assert.Equal("rest", params[0].Name)
+		assert.Equal(`Catch-all parameter, the value may contain "/".`, params[0].Description)
+
+		path, _ = Path(tr.Define("/search?q="))
+		assert.Equal("/search", path)
+
+		path, params = Path(tr.Define(`/posts/:ID<int>/:date<date>/:slug<slug>/:code<int>(^\d{3}$)+.json`))
+		assert.Equal("/posts/{ID}/{date}/{slug}/{code}.json", path)
+		assert.Equal([]Parameter{
+			{Name: "ID", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+			{Name: "date", In: "path", Required: true, Schema: &Schema{Type: "string", Format: "date"}},
+			{Name: "slug", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: `^[a-z0-9]+(?:-[a-z0-9]+)*$`}},
+			{Name: "code", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64", Pattern: `^\d{3}$`}},
+		}, params)
+	})
+
+	t.Run("New", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func(w http.ResponseWriter, req *http.Request, params mux.Params) {}
+		m := mux.New()
+		m.Get("/api/:type/:ID", handler).Meta(&Operation{
+			OperationID: "getTask",
+			Summary:     "Returns the task",
+			Parameters: []Parameter{
+				{Name: "ID", In: "path", Description: "The task ID"},
+				{Name: "fields", In: "query"},
+			},
+			Responses: map[string]Response{"200": {Description: "The task"}},
+		})
+		m.Post(`/api/:type/:ID+:cancel`, handler).Meta(Operation{Summary: "Cancels the task"})
+		m.Delete("/api/:type/:ID", handler)
+		m.Handle("PURGE", "/api/:type/:ID", handler)
+		m.Handle("PURGE", "/cache", handler)
+
+		doc := New(Info{Title: "Tasks", Version: "1.0.0"}, m.GetEndpoints())
+		data, err := json.MarshalIndent(doc, "", "  ")
+		assert.Nil(err)
+		assert.Equal(`{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Tasks",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/api/{type}/{ID}": {
+      "get": {
+        "operationId": "getTask",
+        "summary": "Returns the task",
+        "parameters": [
+          {
+            "name": "type",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "ID",
+            "in": "path",
+            "description": "The task ID",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "fields",
+            "in": "query"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The task"
+          }
+        }
+      },
+      "delete": {
+        "parameters": [
+          {
+            "name": "type",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "ID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "default": {
+            "description": "Default response"
+          }
+        }
+      }
+    },
+    "/api/{type}/{ID}:cancel": {
+      "post": {
+        "summary": "Cancels the task",
+        "parameters": [
+          {
+            "name": "type",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "ID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "default": {
+            "description": "Default response"
+          }
+        }
+      }
+    }
+  }
+}`, string(data))
+
+		item := doc.Paths["/api/{type}/{ID}"]
+		assert.Equal(2, len(item.Operations()))
+		assert.Equal("getTask", item.Operations()["GET"].OperationID)
+		assert.False(item.SetOperation("PURGE", new(Operation)))
+	})
+}
@@ -2,10 +2,16 @@
 package trie
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"path"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Version is trie-mux version
@@ -14,14 +20,17 @@ const Version = "1.5.2"
 // Options is options for Trie.
 type Options struct {
 	// Ignore case when matching URL path.
-	IgnoreCase bool
+	IgnoreCase bool `json:"ignoreCase"`
 
 	// If enabled, the trie will detect if the current path can't be matched but
 	// a handler for the fixed path exists.
 	// Matched.FPR will returns either a fixed redirect path or an empty string.
 	// For example when "/api/foo" defined and matching "/api//foo",
 	// The result Matched.FPR is "/api/foo".
-	FixedPathRedirect bool
+	// The dot segments "." and ".." are removed like path.Clean, the trailing
+	// slash is preserved, e.g. "/api/bar/../foo" is fixed to "/api/foo". A path
+	// whose ".." climbs above a catch-all param is not fixed and not matched.
+	FixedPathRedirect bool `json:"fixedPathRedirect"`
 
 	// If enabled, the trie will detect if the current path can't be matched but
 	// a handler for the path with (without) the trailing slash exists.
@@ -30,7 +39,24 @@ type Options struct {
 	// client is redirected to /foo
 	// For example when "/api/foo" defined and matching "/api/foo/",
 	// The result Matched.TSR is "/api/foo".
-	TrailingSlashRedirect bool
+	TrailingSlashRedirect bool `json:"trailingSlashRedirect"`
+
+	// If enabled, the path to match is escaped, e.g. URL.EscapedPath(). It is
+	// split into segments before unescaping, so that an encoded slash "%2F" is
+	// matched as a part of a segment, and the param values are unescaped one by
+	// one. Matched.FPR and Matched.TSR are escaped.
+	// For example when "/files/:name" defined and matching "/files/a%2Fb",
+	// The result Matched.Params["name"] is "a/b".
+	EscapedPath bool `json:"escapedPath,omitempty"`
+
+	// If enabled, the trie will detect if the current path can't be matched but
+	// a handler for the path in different case exists. It has no effect if
+	// IgnoreCase is enabled.
+	// Matched.CIR will returns either a redirect path with the static segments
+	// in the defined case, or an empty string. The case of param values is kept.
+	// For example when "/api/:user/repos" defined and matching "/API/Alice/Repos",
+	// The result Matched.CIR is "/api/Alice/repos".
+	CaseInsensitiveRedirect bool `json:"caseInsensitiveRedirect,omitempty"`
 }
 
 // the valid characters for the path component:
@@ -38,7 +64,6 @@ type Options struct {
 // http://stackoverflow.com/questions/4669692/valid-characters-for-directory-part-of-a-url-for-short-links
 // https://tools.ietf.org/html/rfc3986#section-3.3
 var (
-	multiSlashReg  = regexp.MustCompile(`/{2,}`)
 	wordReg        = regexp.MustCompile(`^\w+$`)
 	suffixReg      = regexp.MustCompile(`\+[A-Za-z0-9!$%&'*+,-.:;=@_~]*$`)
 	doubleColonReg = regexp.MustCompile(`^::[A-Za-z0-9!$%&'*+,-.:;=@_~]*$`)
@@ -49,6 +74,43 @@ var (
 	}
 )
 
+// Errors returned by Trie.DefineE, Trie.MatchE and Node.HandleE. They are
+// wrapped in a *PatternError, so use errors.Is to test for them.
+var (
+	ErrInvalidPattern   = errors.New("invalid pattern")
+	ErrConflict         = errors.New("conflict pattern")
+	ErrDuplicateHandler = errors.New("duplicate handler")
+	ErrInvalidPath      = errors.New("invalid path")
+	ErrInvalidParam     = errors.New("invalid param")
+)
+
+// PatternError records the pattern and the offending segment of a failed
+// operation on the trie.
+type PatternError struct {
+	// One of ErrInvalidPattern, ErrConflict, ErrDuplicateHandler, ErrInvalidPath
+	// or ErrInvalidParam.
+	Err error
+	// The pattern (or path) being defined, handled or matched.
+	Pattern string
+	// The offending segments, e.g. "/a/:b" when defining "/a/:b/c".
+	Segment string
+
+	msg string
+}
+
+func (e *PatternError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the underlying error value.
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+func newError(err error, segment, format string, args ...interface{}) *PatternError {
+	return &PatternError{Err: err, Segment: segment, msg: fmt.Sprintf(format, args...)}
+}
+
 // New returns a trie
 //
 //  trie := New()
@@ -65,6 +127,8 @@ func New(args ...Options) *Trie {
 		ignoreCase: opts.IgnoreCase,
 		fpr:        opts.FixedPathRedirect,
 		tsr:        opts.TrailingSlashRedirect,
+		escaped:    opts.EscapedPath,
+		cir:        opts.CaseInsensitiveRedirect,
 		root: &Node{
 			parent:   nil,
 			children: make(map[string]*Node),
@@ -78,9 +142,30 @@ type Trie struct {
 	ignoreCase bool
 	fpr        bool
 	tsr        bool
+	escaped    bool
+	cir        bool
 	root       *Node
 }
 
+// GetOptions returns the options of the trie.
+func (t *Trie) GetOptions() Options {
+	return Options{
+		IgnoreCase:              t.ignoreCase,
+		FixedPathRedirect:       t.fpr,
+		TrailingSlashRedirect:   t.tsr,
+		EscapedPath:             t.escaped,
+		CaseInsensitiveRedirect: t.cir,
+	}
+}
+
+// Clone returns a deep copy of the trie, handlers are shared. It is useful to
+// modify a copy of the trie while the original one is serving.
+func (t *Trie) Clone() *Trie {
+	c := *t
+	c.root = t.root.clone(nil)
+	return &c
+}
+
 // GetEndpoints returns all endpoint nodes.
 func (t *Trie) GetEndpoints() []*Node {
 	endpoints := make([]*Node, 0)
@@ -111,54 +196,165 @@ func (t *Trie) GetEndpoints() []*Node {
 // | `:name` | named parameter |
 // | `:name*` | named with catch-all parameter |
 // | `:name(regexp)` | named with regexp parameter |
+// | `:name<int>` | named with constraint parameter, see RegisterConstraint |
 // | `::name` | not named parameter, it is literal `:name` |
 //
 func (t *Trie) Define(pattern string) *Node {
+	node, err := t.DefineE(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
+// DefineE is like Define but returns an error instead of panicking when the
+// pattern is invalid or conflicts with a defined pattern.
+//
+//  node, err := trie.DefineE("/a/:b")
+//  if errors.Is(err, trie.ErrConflict) {
+//  	// ...
+//  }
+//
+func (t *Trie) DefineE(pattern string) (*Node, error) {
 	if strings.Contains(pattern, "//") {
-		panic(fmt.Errorf(`multi-slash exist: "%s"`, pattern))
+		err := newError(ErrInvalidPattern, pattern, `multi-slash exist: "%s"`, pattern)
+		err.Pattern = pattern
+		return nil, err
 	}
 
 	_pattern := strings.TrimPrefix(pattern, "/")
 	if i := strings.IndexRune(_pattern, '?'); i > -1 {
 		_pattern = _pattern[:i]
 	}
-	node := defineNode(t.root, strings.Split(_pattern, "/"), t.ignoreCase)
+	node, err := defineNode(t.root, strings.Split(_pattern, "/"), t.ignoreCase)
+	if err != nil {
+		err.Pattern = pattern
+		return nil, err
+	}
 
 	if node.pattern == "" {
 		node.pattern = pattern
 	}
+	return node, nil
+}
+
+// Lookup returns the endpoint node defined for the pattern, or nil if the
+// pattern is not defined. Unlike Define, it never changes the trie.
+//
+//  node := trie.Define(`/api/:resource/:ID(^\d+$)+:cancel`)
+//  trie.Lookup(`/api/:resource/:ID(^\d+$)+:cancel`) == node
+//  trie.Lookup("/api/:resource") == nil
+//
+func (t *Trie) Lookup(pattern string) *Node {
+	if pattern == "" || strings.Contains(pattern, "//") {
+		return nil
+	}
+
+	_pattern := strings.TrimPrefix(pattern, "/")
+	if i := strings.IndexRune(_pattern, '?'); i > -1 {
+		_pattern = _pattern[:i]
+	}
+	node := lookupNode(t.root, strings.Split(_pattern, "/"), t.ignoreCase)
+	if node == nil || !node.endpoint {
+		return nil
+	}
 	return node
 }
 
+// Remove undefines the pattern and its handlers from the trie, and prunes
+// the branches that are left empty. It returns false if the pattern is not
+// defined. Patterns defined under the pattern are not affected.
+//
+//  trie.Define("/a/b").Handle("GET", handler)
+//  trie.Remove("/a/b") // true
+//  trie.Match("/a/b").Node == nil
+//
+func (t *Trie) Remove(pattern string) bool {
+	node := t.Lookup(pattern)
+	if node == nil {
+		return false
+	}
+	node.remove()
+	return true
+}
+
 // Match try to match path. It will returns a Matched instance that
 // includes	*Node, Params and Tsr flag when matching success, otherwise a nil.
 //
 //  matched := trie.Match("/a/b")
 //
 func (t *Trie) Match(path string) *Matched {
+	matched, err := t.MatchE(path)
+	if err != nil {
+		panic(err)
+	}
+	return matched
+}
+
+// MatchE is like Match but returns an ErrInvalidPath error instead of
+// panicking when the path does not start with "/".
+func (t *Trie) MatchE(path string) (*Matched, error) {
+	matched := new(Matched)
+	if err := t.MatchInto(path, matched); err != nil {
+		return nil, err
+	}
+	if len(matched.Values) > 0 {
+		matched.Params = make(map[string]string, len(matched.Values))
+		for _, param := range matched.Values {
+			matched.Params[param.Name] = param.Value
+		}
+	}
+	return matched, nil
+}
+
+// MatchInto is like MatchE but writes the result into matched, which is reset
+// first. Matched.Params is not filled, use Matched.Values instead. With a
+// Matched reused from AcquireMatched, matching does not allocate.
+//
+//  matched := trie.AcquireMatched()
+//  defer trie.ReleaseMatched(matched)
+//  if err := tr.MatchInto("/a/b", matched); err == nil && matched.Node != nil {
+//  	id := matched.Values.Get("id")
+//  }
+//
+func (t *Trie) MatchInto(path string, matched *Matched) error {
+	matched.Reset()
 	if path == "" || path[0] != '/' {
-		panic(fmt.Errorf(`path is not start with "/": "%s"`, path))
+		err := newError(ErrInvalidPath, path, `path is not start with "/": "%s"`, path)
+		err.Pattern = path
+		return err
 	}
 	fixedLen := len(path)
 	if t.fpr {
+		// the fixed path must not climb above a catch-all param by ".."
+		if t.escapesWildcard(path) {
+			return nil
+		}
 		path = fixPath(path)
 		fixedLen -= len(path)
 	}
 
 	start := 1
 	end := len(path)
-	matched := new(Matched)
 	parent := t.root
 	for i := 1; i <= end; i++ {
 		if i < end && path[i] != '/' {
 			continue
 		}
 		segment := path[start:i]
-		_segment := segment
-		if t.ignoreCase {
-			_segment = strings.ToLower(segment)
+		if t.escaped {
+			var ok bool
+			if segment, ok = unescapeSegment(segment); !ok {
+				return nil
+			}
+		}
+		var node *Node
+		if t.ignoreCase && !isLower(segment) {
+			matched.buf = appendLower(matched.buf[:0], segment)
+			node = matchNodeFold(parent, matched.buf, segment)
+		} else {
+			node = matchNode(parent, segment)
 		}
-		node := matchNode(parent, _segment)
 		if node == nil {
 			// TrailingSlashRedirect: /abc/efg/ -> /abc/efg
 			if t.tsr && parent.endpoint && i == end && segment == "" {
@@ -168,22 +364,33 @@ func (t *Trie) Match(path string) *Matched {
 					matched.TSR = ""
 				}
 			}
-			return matched
+			if matched.TSR == "" && matched.FPR == "" {
+				t.matchCase(path, matched)
+			}
+			return nil
 		}
 
 		parent = node
 		if parent.name != "" {
-			if matched.Params == nil {
-				matched.Params = make(map[string]string)
-			}
 			if parent.wildcard {
-				matched.Params[parent.name] = path[start:end]
+				value := path[start:end]
+				if t.escaped {
+					var ok bool
+					if value, ok = unescapeSegment(value); !ok {
+						return nil
+					}
+				}
+				// a catch-all value never climbs above the param by ".."
+				if strings.Contains(value, "..") && climbs(value) {
+					return nil
+				}
+				matched.Values = append(matched.Values, Param{parent.name, value})
 				break
 			} else {
 				if parent.suffix != "" {
 					segment = segment[0 : len(segment)-len(parent.suffix)]
 				}
-				matched.Params[parent.name] = segment
+				matched.Values = append(matched.Values, Param{parent.name, segment})
 			}
 		}
 		start = i + 1
@@ -203,9 +410,96 @@ func (t *Trie) Match(path string) *Matched {
 			matched.FPR = matched.TSR
 			matched.TSR = ""
 		}
+	default:
+		t.matchCase(path, matched)
 	}
 
-	return matched
+	return nil
+}
+
+// matchCase sets Matched.CIR if CaseInsensitiveRedirect enabled and the path
+// is matched case-insensitively to another path.
+func (t *Trie) matchCase(path string, matched *Matched) {
+	if t.cir && !t.ignoreCase {
+		if fixed, ok := t.matchFold(t.root, path); ok && fixed != path {
+			matched.CIR = fixed
+		}
+	}
+}
+
+// matchFold matches the rest of path under the parent case-insensitively, it
+// returns the rest of path with the static segments in the defined case. The
+// static children in the same case are tried first. Each segment of the path
+// returned leads MatchInto to the same node, so it is matched without
+// backtracking.
+func (t *Trie) matchFold(parent *Node, path string) (string, bool) {
+	if path == "" {
+		return "", parent.endpoint
+	}
+	segment, rest := path[1:], ""
+	if i := strings.IndexByte(segment, '/'); i > -1 {
+		segment, rest = segment[:i], segment[i:]
+	}
+	key, ok := segment, true
+	if t.escaped {
+		if key, ok = unescapeSegment(segment); !ok {
+			return "", false
+		}
+	}
+
+	children := make([]*Node, 0, 1)
+	for k, child := range parent.children {
+		if k != key && strings.EqualFold(k, key) {
+			children = append(children, child)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].key < children[j].key })
+	if child := parent.getChild(key); child != nil {
+		children = append([]*Node{child}, children...)
+	}
+	for _, child := range children {
+		if fixed, ok := t.matchFold(child, rest); ok {
+			s, _ := child.buildSegment(nil)
+			if t.escaped {
+				s = url.PathEscape(s)
+			}
+			return "/" + s + fixed, true
+		}
+	}
+
+	if key == "" {
+		return "", false
+	}
+	for _, child := range parent.varyChildren {
+		if !acceptSegmentFold(child, key) {
+			continue
+		}
+		fixedSegment, fixedKey := segment, key
+		if n := len(child.suffix); n > 0 {
+			fixedSegment = segment[:len(segment)-n] + child.suffix
+			fixedKey = key[:len(key)-n] + child.suffix
+		}
+		// MatchInto never backtracks, the fixed segment must lead to the child
+		if matchNode(parent, fixedKey) != child {
+			continue
+		}
+		if child.wildcard {
+			value := path[1:]
+			if t.escaped {
+				if value, ok = unescapeSegment(value); !ok {
+					return "", false
+				}
+			}
+			if !child.endpoint || strings.Contains(value, "..") && climbs(value) {
+				return "", false
+			}
+			return path, true
+		}
+		if fixed, ok := t.matchFold(child, rest); ok {
+			return "/" + fixedSegment + fixed, true
+		}
+	}
+	return "", false
 }
 
 // Matched is a result returned by Trie.Match.
@@ -214,8 +508,12 @@ type Matched struct {
 	Node *Node
 
 	// Either a map contained matched values or empty map.
+	// It is not filled by Trie.MatchInto.
 	Params map[string]string
 
+	// Matched values in the order of the pattern.
+	Values Params
+
 	// If FixedPathRedirect enabled, it may returns a redirect path,
 	// otherwise a empty string.
 	FPR string
@@ -223,17 +521,80 @@ type Matched struct {
 	// If TrailingSlashRedirect enabled, it may returns a redirect path,
 	// otherwise a empty string.
 	TSR string
+
+	// If CaseInsensitiveRedirect enabled, it may returns a redirect path,
+	// otherwise a empty string.
+	CIR string
+
+	buf []byte // scratch buffer for case folding
+}
+
+var matchedPool = sync.Pool{
+	New: func() interface{} { return new(Matched) },
+}
+
+// AcquireMatched returns an empty Matched from the pool, use it with
+// Trie.MatchInto and return it by ReleaseMatched.
+func AcquireMatched() *Matched {
+	return matchedPool.Get().(*Matched)
+}
+
+// ReleaseMatched resets the Matched and puts it back to the pool. It should
+// not be used after released.
+func ReleaseMatched(m *Matched) {
+	m.Reset()
+	matchedPool.Put(m)
+}
+
+// Reset resets the Matched to be reused, the capacity of Values is kept.
+func (m *Matched) Reset() {
+	m.Node = nil
+	m.Params = nil
+	m.Values = m.Values[:0]
+	m.FPR = ""
+	m.TSR = ""
+	m.CIR = ""
+}
+
+// Param is a matched parameter, consisting of a name and a value.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Params is a list of matched parameters in the order of the pattern.
+type Params []Param
+
+// Get returns the value of the parameter by name, or an empty string.
+func (ps Params) Get(name string) string {
+	for i := range ps {
+		if ps[i].Name == name {
+			return ps[i].Value
+		}
+	}
+	return ""
+}
+
+// ByIndex returns the i-th parameter, or an empty Param if out of range.
+func (ps Params) ByIndex(i int) Param {
+	if i < 0 || i >= len(ps) {
+		return Param{}
+	}
+	return ps[i]
 }
 
 // Node represents a node on defined patterns that can be matched.
 type Node struct {
 	name, allow, pattern, segment, suffix string
+	key, constraint                       string
 	endpoint, wildcard                    bool
 	parent                                *Node
 	varyChildren                          []*Node
 	children                              map[string]*Node
 	handlers                              map[string]interface{}
+	meta                                  map[string]interface{}
 	regex                                 *regexp.Regexp
+	check                                 Constraint
 }
 
 func (n *Node) getSegments() string {
@@ -256,8 +617,19 @@ func (n *Node) getChild(key string) *Node {
 //  node.Handle("POST", handler1)
 //
 func (n *Node) Handle(method string, handler interface{}) {
+	if err := n.HandleE(method, handler); err != nil {
+		panic(err)
+	}
+}
+
+// HandleE is like Handle but returns an ErrDuplicateHandler error instead of
+// panicking when a handler for the method is already defined.
+func (n *Node) HandleE(method string, handler interface{}) error {
 	if n.GetHandler(method) != nil {
-		panic(fmt.Errorf(`"%s" already defined`, n.getSegments()))
+		segments := n.getSegments()
+		err := newError(ErrDuplicateHandler, segments, `"%s" already defined`, segments)
+		err.Pattern = n.pattern
+		return err
 	}
 	n.handlers[method] = handler
 	if n.allow == "" {
@@ -265,6 +637,52 @@ func (n *Node) Handle(method string, handler interface{}) {
 	} else {
 		n.allow += ", " + method
 	}
+	return nil
+}
+
+// Unhandle removes the handler for the method from the node and returns true,
+// or returns false if no handler defined for the method. When the last
+// handler is removed, the node is no longer an endpoint and is removed from
+// the trie like Trie.Remove.
+//
+//  node.Handle("GET", handler1)
+//  node.Unhandle("GET") // true
+//
+func (n *Node) Unhandle(method string) bool {
+	if n.GetHandler(method) == nil {
+		return false
+	}
+	delete(n.handlers, method)
+	delete(n.meta, method)
+	allow := strings.Split(n.allow, ", ")
+	for i, m := range allow {
+		if m == method {
+			allow = append(allow[:i], allow[i+1:]...)
+			break
+		}
+	}
+	n.allow = strings.Join(allow, ", ")
+	if len(n.handlers) == 0 {
+		n.remove()
+	}
+	return true
+}
+
+// Replace mounts the handler with the method to the node like Handle, but
+// replaces the handler already defined instead of panicking. It returns the
+// replaced handler, or nil.
+//
+//  node.Handle("GET", handler1)
+//  node.Replace("GET", handler2) // handler1
+//
+func (n *Node) Replace(method string, handler interface{}) interface{} {
+	prev := n.GetHandler(method)
+	if prev == nil {
+		n.Handle(method, handler)
+		return nil
+	}
+	n.handlers[method] = handler
+	return prev
 }
 
 // GetHandler ...
@@ -281,6 +699,28 @@ func (n *Node) GetHandler(method string) interface{} {
 	return n.handlers[method]
 }
 
+// SetMeta attaches metadata to the handler of the method, such as a summary
+// for API documents. It returns false if no handler defined for the method.
+//
+//  node.Handle("GET", handler1)
+//  node.SetMeta("GET", "Returns the task")
+//
+func (n *Node) SetMeta(method string, meta interface{}) bool {
+	if n.GetHandler(method) == nil {
+		return false
+	}
+	if n.meta == nil {
+		n.meta = make(map[string]interface{})
+	}
+	n.meta[method] = meta
+	return true
+}
+
+// GetMeta returns the metadata attached to the handler of the method, or nil.
+func (n *Node) GetMeta(method string) interface{} {
+	return n.meta[method]
+}
+
 // GetAllow returns allow methods defined on the node
 //
 //  trie := New()
@@ -298,6 +738,75 @@ func (n *Node) GetPattern() string {
 	return n.pattern
 }
 
+// URL builds a URL path for the node with the given parameter values. Values are
+// escaped, suffixes are re-appended and regexp constraints are validated.
+// The value of a catch-all parameter may contain "/".
+//
+//  node := trie.Define(`/api/:resource/:ID(^\d+$)+:cancel`)
+//  node.URL(map[string]string{"resource": "task", "ID": "123"})
+//  // "/api/task/123:cancel", nil
+//
+func (n *Node) URL(params map[string]string) (string, error) {
+	segments := make([]string, 0)
+	for node := n; node.parent != nil; node = node.parent {
+		segment, err := node.buildSegment(params)
+		if err != nil {
+			return "", err
+		}
+		segments = append(segments, segment)
+	}
+
+	var b strings.Builder
+	for i := len(segments) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(segments[i])
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
+
+func (n *Node) buildSegment(params map[string]string) (string, *PatternError) {
+	switch {
+	case n.name == "" && n.wildcard:
+		return n.segment[:len(n.segment)-1], nil
+	case n.name == "" && doubleColonReg.MatchString(n.segment):
+		return n.segment[1:], nil
+	case n.name == "":
+		return n.segment, nil
+	}
+
+	value := params[n.name]
+	if value == "" {
+		return "", n.invalidParam(`param "%s" is required`)
+	}
+	if n.wildcard {
+		parts := strings.Split(value, "/")
+		for i, part := range parts {
+			parts[i] = url.PathEscape(part)
+		}
+		return strings.Join(parts, "/"), nil
+	}
+	if strings.IndexByte(value, '/') > -1 {
+		return "", n.invalidParam(`param "%s" should not contain "/"`)
+	}
+	if n.regex != nil && !n.regex.MatchString(value) {
+		return "", n.invalidParam(`param "%s" not match the regexp`)
+	}
+	if n.check != nil && !n.check(value) {
+		return "", n.invalidParam(`param "%s" not match the constraint`)
+	}
+	return url.PathEscape(value) + n.suffix, nil
+}
+
+func (n *Node) invalidParam(format string) *PatternError {
+	segments := n.getSegments()
+	err := newError(ErrInvalidParam, segments, format+` in "%s"`, n.name, segments)
+	err.Pattern = n.pattern
+	return err
+}
+
 // GetMethods returns methods defined on the node
 func (n *Node) GetMethods() []string {
 	methods := make([]string, 0, len(n.handlers))
@@ -307,6 +816,46 @@ func (n *Node) GetMethods() []string {
 	return methods
 }
 
+func (n *Node) clone(parent *Node) *Node {
+	c := *n
+	c.parent = parent
+	c.children = make(map[string]*Node, len(n.children))
+	for key, child := range n.children {
+		c.children[key] = child.clone(&c)
+	}
+	if n.varyChildren != nil {
+		c.varyChildren = make([]*Node, len(n.varyChildren))
+		for i, child := range n.varyChildren {
+			c.varyChildren[i] = child.clone(&c)
+		}
+	}
+	c.handlers = make(map[string]interface{}, len(n.handlers))
+	for method, handler := range n.handlers {
+		c.handlers[method] = handler
+	}
+	if n.meta != nil {
+		c.meta = make(map[string]interface{}, len(n.meta))
+		for method, meta := range n.meta {
+			c.meta[method] = meta
+		}
+	}
+	return &c
+}
+
+func (n *Node) remove() {
+	n.endpoint = false
+	n.pattern = ""
+	n.allow = ""
+	n.handlers = make(map[string]interface{})
+	n.meta = nil
+
+	// prune empty branches
+	for node := n; node.parent != nil && !node.endpoint &&
+		len(node.children) == 0 && len(node.varyChildren) == 0; node = node.parent {
+		unlinkNode(node.parent, node)
+	}
+}
+
 // GetDescendants returns all descendants nodes.
 func (n *Node) GetDescendants() []*Node {
 	nodes := make([]*Node, 0)
@@ -321,19 +870,62 @@ func (n *Node) GetDescendants() []*Node {
 	return nodes
 }
 
-func defineNode(parent *Node, segments []string, ignoreCase bool) *Node {
+func defineNode(parent *Node, segments []string, ignoreCase bool) (*Node, *PatternError) {
 	segment := segments[0]
 	segments = segments[1:]
-	child := parseNode(parent, segment, ignoreCase)
+	child, exist, err := parseNode(parent, segment, ignoreCase)
+	if err != nil {
+		return nil, err
+	}
+	if exist != nil {
+		child = exist
+	} else {
+		insertNode(parent, child)
+	}
 
 	if len(segments) == 0 {
 		child.endpoint = true
-		return child
+		return child, nil
 	}
+	var node *Node
 	if child.wildcard {
-		panic(fmt.Errorf(`can't define pattern after wildcard: "%s"`, child.getSegments()))
+		segments := child.getSegments()
+		err = newError(ErrInvalidPattern, segments, `can't define pattern after wildcard: "%s"`, segments)
+	} else {
+		node, err = defineNode(child, segments, ignoreCase)
+	}
+	if err != nil && exist == nil {
+		// a failed define leaves the trie unchanged
+		unlinkNode(parent, child)
 	}
-	return defineNode(child, segments, ignoreCase)
+	return node, err
+}
+
+// unlinkNode removes the child node from the parent.
+func unlinkNode(parent, child *Node) {
+	if child.name == "" {
+		if parent.children[child.key] == child {
+			delete(parent.children, child.key)
+		}
+		return
+	}
+	for i, node := range parent.varyChildren {
+		if node == child {
+			parent.varyChildren = append(parent.varyChildren[:i], parent.varyChildren[i+1:]...)
+			return
+		}
+	}
+}
+
+func lookupNode(parent *Node, segments []string, ignoreCase bool) *Node {
+	for _, segment := range segments {
+		_, exist, err := parseNode(parent, segment, ignoreCase)
+		if err != nil || exist == nil {
+			return nil
+		}
+		parent = exist
+	}
+	return parent
 }
 
 func matchNode(parent *Node, segment string) (child *Node) {
@@ -341,14 +933,64 @@ func matchNode(parent *Node, segment string) (child *Node) {
 		return
 	}
 	for _, child = range parent.varyChildren {
-		_segment := segment
+		if acceptSegment(child, segment) {
+			return
+		}
+	}
+	return nil
+}
+
+// acceptSegment reports whether the param node accepts the segment, it runs
+// on every matching so it must not allocate.
+func acceptSegment(n *Node, segment string) bool {
+	if n.suffix != "" {
+		if segment == n.suffix || !strings.HasSuffix(segment, n.suffix) {
+			return false
+		}
+		segment = segment[0 : len(segment)-len(n.suffix)]
+	}
+	return acceptValue(n, segment)
+}
+
+// acceptSegmentFold is like acceptSegment, but the suffix is matched
+// case-insensitively.
+func acceptSegmentFold(n *Node, segment string) bool {
+	if n.suffix != "" {
+		i := len(segment) - len(n.suffix)
+		if i <= 0 || !strings.EqualFold(segment[i:], n.suffix) {
+			return false
+		}
+		segment = segment[:i]
+	}
+	return acceptValue(n, segment)
+}
+
+// acceptValue reports whether the param value without suffix is accepted by
+// the regexp and the constraint of the param node.
+func acceptValue(n *Node, value string) bool {
+	return (n.regex == nil || n.regex.MatchString(value)) && (n.check == nil || n.check(value))
+}
+
+// matchNodeFold is like matchNode, but with a lowercased segment in bytes. The
+// constraints check the value as in the path, the one of the param.
+func matchNodeFold(parent *Node, segment []byte, value string) (child *Node) {
+	// string(segment) in map index and comparison does not allocate
+	if child = parent.children[string(segment)]; child != nil || len(segment) == 0 {
+		return
+	}
+	for _, child = range parent.varyChildren {
+		_segment, _value := segment, value
 		if child.suffix != "" {
-			if segment == child.suffix || !strings.HasSuffix(segment, child.suffix) {
+			if string(segment) == child.suffix || !hasSuffix(segment, child.suffix) || len(value) < len(child.suffix) {
 				continue
 			}
 			_segment = segment[0 : len(segment)-len(child.suffix)]
+			_value = value[0 : len(value)-len(child.suffix)]
+		}
+		if child.regex != nil && !child.regex.Match(_segment) {
+			continue
 		}
-		if child.regex != nil && !child.regex.MatchString(_segment) {
+		if child.check != nil && !child.check(_value) {
 			continue
 		}
 		return
@@ -356,7 +998,40 @@ func matchNode(parent *Node, segment string) (child *Node) {
 	return nil
 }
 
-func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
+func hasSuffix(b []byte, suffix string) bool {
+	return len(b) >= len(suffix) && string(b[len(b)-len(suffix):]) == suffix
+}
+
+// isLower reports whether strings.ToLower(s) == s for ASCII s.
+func isLower(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf || 'A' <= c && c <= 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// appendLower appends strings.ToLower(s) to b.
+func appendLower(b []byte, s string) []byte {
+	var buf [utf8.UTFMax]byte
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			if 'A' <= r && r <= 'Z' {
+				r += 'a' - 'A'
+			}
+			b = append(b, byte(r))
+			continue
+		}
+		n := utf8.EncodeRune(buf[:], unicode.ToLower(r))
+		b = append(b, buf[:n]...)
+	}
+	return b
+}
+
+// parseNode parses the segment to a new child node of the parent. If an
+// equivalent node is already defined on the parent, it is returned as exist.
+func parseNode(parent *Node, segment string, ignoreCase bool) (node, exist *Node, err *PatternError) {
 	_segment := segment
 	if doubleColonReg.MatchString(segment) {
 		_segment = segment[1:]
@@ -364,11 +1039,11 @@ func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
 	if ignoreCase {
 		_segment = strings.ToLower(_segment)
 	}
-	if node := parent.getChild(_segment); node != nil {
-		return node
+	if exist = parent.getChild(_segment); exist != nil {
+		return
 	}
 
-	node := &Node{
+	node = &Node{
 		segment:  segment,
 		parent:   parent,
 		children: make(map[string]*Node),
@@ -377,16 +1052,19 @@ func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
 
 	switch {
 	case segment == "":
-		parent.children[segment] = node
+		node.key = segment
 
 	case doubleColonReg.MatchString(segment):
 		// pattern "/a/::" should match "/a/:"
 		// pattern "/a/::bc" should match "/a/:bc"
 		// pattern "/a/::/bc" should match "/a/:/bc"
-		parent.children[_segment] = node
+		node.key = _segment
 
 	case segment[0] == ':':
 		name := segment[1:]
+		if name == "" {
+			return nil, nil, invalidPattern(node)
+		}
 
 		switch name[len(name)-1] {
 		case '*':
@@ -398,8 +1076,8 @@ func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
 			if suffix != "" {
 				name = name[0 : len(name)-len(suffix)]
 				node.suffix = suffix[1:]
-				if node.suffix == "" {
-					panic(fmt.Errorf(`invalid pattern: "%s"`, node.getSegments()))
+				if node.suffix == "" || name == "" {
+					return nil, nil, invalidPattern(node)
 				}
 			}
 
@@ -408,9 +1086,23 @@ func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
 					var regex = name[index+1 : len(name)-1]
 					if len(regex) > 0 {
 						name = name[0:index]
-						node.regex = regexp.MustCompile(regex)
+						re, e := regexp.Compile(regex)
+						if e != nil {
+							return nil, nil, invalidPattern(node)
+						}
+						node.regex = re
 					} else {
-						panic(fmt.Errorf(`invalid pattern: "%s"`, node.getSegments()))
+						return nil, nil, invalidPattern(node)
+					}
+				}
+			}
+
+			if name[len(name)-1] == '>' {
+				if index := strings.IndexRune(name, '<'); index > 0 {
+					node.constraint = name[index+1 : len(name)-1]
+					name = name[0:index]
+					if node.check = getConstraint(node.constraint); node.check == nil {
+						return nil, nil, invalidPattern(node)
 					}
 				}
 			}
@@ -418,66 +1110,180 @@ func parseNode(parent *Node, segment string, ignoreCase bool) *Node {
 
 		// name must be word characters `[0-9A-Za-z_]`
 		if !wordReg.MatchString(name) {
-			panic(fmt.Errorf(`invalid pattern: "%s"`, node.getSegments()))
+			return nil, nil, invalidPattern(node)
 		}
 		node.name = name
 		// check if node exists
 		for _, child := range parent.varyChildren {
 			if child.wildcard {
 				if !node.wildcard {
-					panic(fmt.Errorf(`can't define "%s" after "%s"`, node.getSegments(), child.getSegments()))
+					segments := node.getSegments()
+					return nil, nil, newError(ErrConflict, segments, `can't define "%s" after "%s"`, segments, child.getSegments())
 				}
 				if child.name != node.name {
-					panic(fmt.Errorf(`invalid pattern name "%s", as prev defined "%s"`, node.name, child.getSegments()))
+					return nil, nil, newError(ErrConflict, node.getSegments(), `invalid pattern name "%s", as prev defined "%s"`, node.name, child.getSegments())
 				}
-				return child
+				return node, child, nil
 			}
 
-			if child.suffix != node.suffix {
+			if child.suffix != node.suffix || child.constraint != node.constraint {
 				continue
 			}
 
 			if !node.wildcard && (child.regex == nil && node.regex == nil) ||
 				child.regex != nil && node.regex != nil && child.regex.String() == node.regex.String() {
 				if child.name != node.name {
-					panic(fmt.Errorf(`invalid pattern name "%s", as prev defined "%s"`, node.name, child.getSegments()))
+					return nil, nil, newError(ErrConflict, node.getSegments(), `invalid pattern name "%s", as prev defined "%s"`, node.name, child.getSegments())
 				}
-				return child
+				return node, child, nil
 			}
 		}
-		parent.varyChildren = append(parent.varyChildren, node)
-		if s := parent.varyChildren; len(s) > 1 {
-			sort.SliceStable(s, func(i, j int) bool {
-				// i > j
-				switch {
-				case s[i].suffix == "" && s[j].suffix != "":
-					return false
-				case s[i].suffix != "" && s[j].suffix == "":
-					return true
-				case s[i].regex != nil && s[j].regex == nil:
-					return true
-				default:
-					return false
-				}
-			})
-		}
 
 	case segment[0] == '*' || segment[0] == '(' || segment[0] == ')':
-		panic(fmt.Errorf(`invalid pattern: "%s"`, node.getSegments()))
+		return nil, nil, invalidPattern(node)
 
 	case segment[len(segment)-1] == '*':
 		node.wildcard = true
-		parent.children[_segment[0:len(_segment)-1]] = node
+		node.key = _segment[0 : len(_segment)-1]
 	default:
-		parent.children[_segment] = node
+		node.key = _segment
 	}
 
-	return node
+	return node, nil, nil
+}
+
+func insertNode(parent *Node, node *Node) {
+	if node.name == "" {
+		parent.children[node.key] = node
+		return
+	}
+
+	parent.varyChildren = append(parent.varyChildren, node)
+	if s := parent.varyChildren; len(s) > 1 {
+		sort.SliceStable(s, func(i, j int) bool {
+			// i > j
+			switch {
+			case s[i].suffix == "" && s[j].suffix != "":
+				return false
+			case s[i].suffix != "" && s[j].suffix == "":
+				return true
+			case s[i].regex != nil && s[j].regex == nil:
+				return true
+			case s[i].check != nil && s[j].check == nil && s[j].regex == nil:
+				return true
+			default:
+				return false
+			}
+		})
+	}
+}
+
+func invalidPattern(node *Node) *PatternError {
+	segments := node.getSegments()
+	return newError(ErrInvalidPattern, segments, `invalid pattern: "%s"`, segments)
 }
 
-func fixPath(path string) string {
-	if !strings.Contains(path, "//") {
-		return path
+// unescapeSegment unescapes an escaped segment, it returns false if the
+// segment is not a valid escaping.
+func unescapeSegment(segment string) (string, bool) {
+	if strings.IndexByte(segment, '%') < 0 {
+		return segment, true
+	}
+	s, err := url.PathUnescape(segment)
+	return s, err == nil
+}
+
+// fixPath collapses repeated slashes and removes the dot segments "." and ".."
+// of the path like path.Clean, but the trailing slash is preserved.
+func fixPath(p string) string {
+	if !strings.Contains(p, "//") && !hasDotSegment(p) {
+		return p
+	}
+	fixed := path.Clean(p)
+	if p[len(p)-1] == '/' && fixed != "/" {
+		fixed += "/"
+	}
+	return fixed
+}
+
+// hasDotSegment reports whether the path has a "." or ".." segment.
+func hasDotSegment(p string) bool {
+	for i := 1; i < len(p); i++ {
+		if p[i] != '.' || p[i-1] != '/' {
+			continue
+		}
+		j := i + 1
+		if j < len(p) && p[j] == '.' {
+			j++
+		}
+		if j == len(p) || p[j] == '/' {
+			return true
+		}
+	}
+	return false
+}
+
+// climbs reports whether the ".." segments of the relative path climb above it,
+// e.g. "a/../../b".
+func climbs(p string) bool {
+	depth := 0
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if depth--; depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
+
+// escapesWildcard reports whether the path matches a catch-all param before
+// removing the dot segments, and the ".." segments climb above the param. Such
+// a path is not fixed, so that a catch-all mount can't be escaped.
+//
+//  tr.Define("/static/:filepath*")
+//  tr.escapesWildcard("/static/a/../../admin") // true
+//
+func (t *Trie) escapesWildcard(p string) bool {
+	if !hasDotSegment(p) {
+		return false
+	}
+	start := 1
+	parent := t.root
+	for i := 1; i <= len(p); i++ {
+		if i < len(p) && p[i] != '/' {
+			continue
+		}
+		segment := p[start:i]
+		switch {
+		case segment == "":
+			// repeated slashes are collapsed
+			start = i + 1
+			continue
+		case t.escaped:
+			var ok bool
+			if segment, ok = unescapeSegment(segment); !ok {
+				return false
+			}
+		}
+		if t.ignoreCase {
+			segment = strings.ToLower(segment)
+		}
+		if parent = matchNode(parent, segment); parent == nil {
+			return false
+		}
+		if parent.name != "" && parent.wildcard {
+			return climbs(p[start:])
+		}
+		if segment == "." || segment == ".." {
+			// the dot segments are removed before reaching a catch-all param
+			return false
+		}
+		start = i + 1
 	}
-	return multiSlashReg.ReplaceAllString(path, "/")
+	return false
 }
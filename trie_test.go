@@ -1,8 +1,10 @@
 package trie
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -338,6 +340,70 @@ func TestGearTrieDefine(t *testing.T) {
 		node = tr.Define("/::A/b")
 		NotEqualPtr(t, node, tr.Define("/::a/b"))
 	})
+	t.Run("DefineE", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node, err := tr.DefineE("/a/:b")
+		assert.Nil(err)
+		EqualPtr(t, node, tr.Define("/a/:b"))
+
+		_, err = tr.DefineE("/a//b")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		assert.Equal(`multi-slash exist: "/a//b"`, err.Error())
+
+		_, err = tr.DefineE("/a/:b(")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		_, err = tr.DefineE("/a/:")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		_, err = tr.DefineE("/a/:+x")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		assert.Nil(tr.Lookup("/a/:"))
+		assert.Nil(tr.Lookup("/a/:+x"))
+
+		_, err = tr.DefineE("/a/:x/c")
+		assert.True(errors.Is(err, ErrConflict))
+		perr := err.(*PatternError)
+		assert.Equal("/a/:x/c", perr.Pattern)
+		assert.Equal("/a/:x", perr.Segment)
+
+		tr.Define("/w/:p*")
+		_, err = tr.DefineE("/w/:p*/c")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		_, err = tr.DefineE("/w/:q")
+		assert.True(errors.Is(err, ErrConflict))
+
+		_, err = tr.DefineE("/a/:bc(^(x$)")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		assert.Panics(func() {
+			tr.Define("/a/:bc(^(x$)")
+		})
+	})
+
+	t.Run("failed DefineE leaves the trie unchanged", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		tr.Define("/b/c")
+		descendants := len(tr.root.GetDescendants())
+
+		_, err := tr.DefineE("/a/:x*/b")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		assert.Equal(descendants, len(tr.root.GetDescendants()))
+		_, err = tr.DefineE("/a/:y")
+		assert.Nil(err)
+
+		tr = New()
+		tr.Define("/b/c")
+		_, err = tr.DefineE("/a/:x/:y(")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		_, err = tr.DefineE("/b/c/:x/d/:y*/e")
+		assert.True(errors.Is(err, ErrInvalidPattern))
+		assert.Equal(descendants, len(tr.root.GetDescendants()))
+		assert.Nil(tr.Match("/a/b").Node)
+		_, err = tr.DefineE("/a/:z")
+		assert.Nil(err)
+	})
 }
 
 func TestGearTrieMatch(t *testing.T) {
@@ -355,6 +421,12 @@ func TestGearTrieMatch(t *testing.T) {
 		})
 
 		assert.Nil(tr1.Match("/a").Node)
+
+		_, err := tr1.MatchE("a")
+		assert.True(errors.Is(err, ErrInvalidPath))
+		res, err = tr1.MatchE("/")
+		assert.Nil(err)
+		EqualPtr(t, node, res.Node)
 	})
 
 	t.Run("simple pattern", func(t *testing.T) {
@@ -667,6 +739,47 @@ func TestGearTrieMatch(t *testing.T) {
 		assert.Equal("/abc/xyz/", tr.Match("/abc/xyz//").FPR)
 		assert.Nil(tr.Match("/abc/xyz////").Node)
 		assert.Equal("/abc/xyz/", tr.Match("/abc/xyz////").FPR)
+
+		// dot segments
+		assert.Nil(tr.Match("/abc/./efg").Node)
+		assert.Equal("/abc/efg", tr.Match("/abc/./efg").FPR)
+		assert.Equal("/abc/efg", tr.Match("/abc/xyz/../efg").FPR)
+		assert.Equal("/abc/efg", tr.Match("/../abc//efg/.").FPR)
+		assert.Equal("/abc/xyz/", tr.Match("/abc/xyz/./").FPR)
+		assert.Equal("/abc/xyz/", tr.Match("/abc/xyz/efg/../").FPR)
+		assert.Equal("", tr.Match("/abc/efg/..").FPR)
+		assert.Equal("", tr.Match("/abc/.efg").FPR)
+		assert.Equal("", tr.Match("/abc/efg..").FPR)
+
+		// dot segments with TrailingSlashRedirect
+		tr = New(Options{FixedPathRedirect: true, TrailingSlashRedirect: true})
+		tr.Define("/abc/xyz/")
+		tr.Define("/abc/efg")
+		assert.Equal("/abc/xyz/", tr.Match("/abc/xyz/efg/..").FPR)
+		assert.Equal("/abc/efg", tr.Match("/abc/./efg/").FPR)
+
+		// ".." never climbs above a catch-all param
+		tr = New(Options{FixedPathRedirect: true})
+		node1 = tr.Define("/static/:filepath*")
+		node2 = tr.Define("/admin")
+		res := tr.Match("/static/a/b/../c")
+		assert.Nil(res.Node)
+		assert.Equal("/static/a/c", res.FPR)
+		assert.Equal("", tr.Match("/static/../admin").FPR)
+		assert.Equal("", tr.Match("/static/../static/c").FPR)
+		assert.Equal("/admin", tr.Match("/admin/.").FPR)
+		res = tr.Match("/static/a/../../admin")
+		assert.Nil(res.Node)
+		assert.Equal("", res.FPR)
+		assert.Equal("", tr.Match("/static//a/.././../admin").FPR)
+
+		tr = New(Options{})
+		node1 = tr.Define("/static/:filepath*")
+		EqualPtr(t, node1, tr.Match("/static/a/../b").Node)
+		assert.Equal("a/../b", tr.Match("/static/a/../b").Params["filepath"])
+		EqualPtr(t, node1, tr.Match("/static/..a/b..").Node)
+		assert.Nil(tr.Match("/static/a/../../admin").Node)
+		assert.Nil(tr.Match("/static/../admin").Node)
 	})
 
 	t.Run("TrailingSlashRedirect option", func(t *testing.T) {
@@ -733,6 +846,158 @@ func TestGearTrieMatch(t *testing.T) {
 		assert.Nil(tr.Match("/abc/").Node)
 		assert.Equal("/abc", tr.Match("/abc/").TSR)
 	})
+
+	t.Run("EscapedPath option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New(Options{EscapedPath: true, IgnoreCase: true, FixedPathRedirect: true, TrailingSlashRedirect: true})
+		node1 := tr.Define("/files/:name")
+		node2 := tr.Define("/files/:name/raw")
+		node3 := tr.Define("/static/:filepath*")
+		node4 := tr.Define("/café/:ID+.json")
+
+		res := tr.Match("/files/a%2Fb")
+		EqualPtr(t, node1, res.Node)
+		assert.Equal("a/b", res.Params["name"])
+
+		res = tr.Match("/files/a%20b%2Fc/raw")
+		EqualPtr(t, node2, res.Node)
+		assert.Equal("a b/c", res.Params["name"])
+
+		res = tr.Match("/static/a%2Fb/c%20d")
+		EqualPtr(t, node3, res.Node)
+		assert.Equal("a/b/c d", res.Params["filepath"])
+
+		res = tr.Match("/CAF%C3%A9/a%2Eb.json")
+		EqualPtr(t, node4, res.Node)
+		assert.Equal("a.b", res.Params["ID"])
+
+		// invalid escaping is not matched
+		assert.Nil(tr.Match("/files/a%2").Node)
+		assert.Nil(tr.Match("/static/a/%zz").Node)
+
+		// redirects are escaped
+		assert.Equal("/files/a%2Fb", tr.Match("/files/a%2Fb/").TSR)
+		assert.Equal("/files/a%2Fb/raw", tr.Match("//files/a%2Fb/raw").FPR)
+
+		// not enabled
+		tr = New(Options{})
+		tr.Define("/files/:name")
+		assert.Nil(tr.Match("/files/a/b").Node)
+		assert.Equal("a%2Fb", tr.Match("/files/a%2Fb").Params["name"])
+	})
+
+	t.Run("CaseInsensitiveRedirect option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New(Options{CaseInsensitiveRedirect: true})
+		node1 := tr.Define("/api/:user/repos")
+		node2 := tr.Define("/API/v2")
+		node3 := tr.Define("/api/v2/:ID+.JSON")
+		tr.Define("/files/:filepath*")
+		tr.Define("/Docs/")
+
+		EqualPtr(t, node1, tr.Match("/api/Alice/repos").Node)
+		assert.Equal("", tr.Match("/api/Alice/repos").CIR)
+		EqualPtr(t, node2, tr.Match("/API/v2").Node)
+
+		res := tr.Match("/API/Alice/Repos")
+		assert.Nil(res.Node)
+		assert.Equal("/api/Alice/repos", res.CIR)
+		assert.Equal("/API/v2", tr.Match("/api/V2").CIR)
+		assert.Equal("/api/v2/Ab.JSON", tr.Match("/Api/V2/Ab.json").CIR)
+		EqualPtr(t, node3, tr.Match("/api/v2/Ab.JSON").Node)
+		assert.Equal("/files/A/B", tr.Match("/FILES/A/B").CIR)
+		assert.Equal("/Docs/", tr.Match("/docs/").CIR)
+		assert.Equal("", tr.Match("/docs").CIR)
+		assert.Equal("", tr.Match("/api/Alice").CIR)
+		assert.Equal("", tr.Match("/API/Alice/Repos/x").CIR)
+
+		// the static segments in the same case are tried first
+		tr.Define("/api/:user/Repos")
+		assert.Equal("/api/Alice/Repos", tr.Match("/API/Alice/Repos").CIR)
+		assert.Equal("/api/Alice/Repos", tr.Match("/API/Alice/REPOS").CIR)
+
+		// the redirect path is matched without backtracking
+		tr = New(Options{CaseInsensitiveRedirect: true})
+		node1 = tr.Define("/a/:x/b")
+		node2 = tr.Define("/a/c/d")
+		res = tr.Match("/a/c/b")
+		assert.Nil(res.Node)
+		assert.Equal("", res.CIR)
+		assert.Equal("/a/c/d", tr.Match("/a/C/D").CIR)
+		EqualPtr(t, node1, tr.Match("/a/C/b").Node)
+		EqualPtr(t, node2, tr.Match(tr.Match("/A/c/D").CIR).Node)
+
+		// with FixedPathRedirect
+		tr = New(Options{CaseInsensitiveRedirect: true, FixedPathRedirect: true, TrailingSlashRedirect: true})
+		tr.Define("/api/users")
+		tr.Define("/api/tasks/")
+		assert.Equal("/api/users", tr.Match("/API//users").CIR)
+		assert.Equal("", tr.Match("/API//users").FPR)
+		assert.Equal("/api/users", tr.Match("//api/users").FPR)
+		assert.Equal("/api/tasks/", tr.Match("/api/tasks").TSR)
+		assert.Equal("", tr.Match("/API/tasks").CIR)
+
+		// with EscapedPath
+		tr = New(Options{CaseInsensitiveRedirect: true, EscapedPath: true})
+		tr.Define("/Files/:name")
+		assert.Equal("/Files/A%2Fb", tr.Match("/files/A%2Fb").CIR)
+
+		// IgnoreCase takes precedence
+		tr = New(Options{CaseInsensitiveRedirect: true, IgnoreCase: true})
+		node1 = tr.Define("/api/users")
+		EqualPtr(t, node1, tr.Match("/API/Users").Node)
+		assert.Equal("", tr.Match("/API/Users").CIR)
+
+		// not enabled
+		tr = New(Options{})
+		tr.Define("/api/users")
+		assert.Equal("", tr.Match("/API/Users").CIR)
+	})
+
+	t.Run("MatchInto", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node1 := tr.Define("/api/:type/:ID(^\\d+$)")
+		node2 := tr.Define("/Files/:filepath*")
+		node3 := tr.Define("/a/:b(^[a-z]+$)+:del")
+
+		matched := AcquireMatched()
+		defer ReleaseMatched(matched)
+		assert.Nil(tr.MatchInto("/api/user/123", matched))
+		EqualPtr(t, node1, matched.Node)
+		assert.Nil(matched.Params)
+		assert.Equal(Params{{"type", "user"}, {"ID", "123"}}, matched.Values)
+		assert.Equal("123", matched.Values.Get("ID"))
+		assert.Equal("", matched.Values.Get("id"))
+		assert.Equal(Param{"type", "user"}, matched.Values.ByIndex(0))
+		assert.Equal(Param{}, matched.Values.ByIndex(2))
+
+		assert.Nil(tr.MatchInto("/FILES/ÄB/汉", matched))
+		EqualPtr(t, node2, matched.Node)
+		assert.Equal(Params{{"filepath", "ÄB/汉"}}, matched.Values)
+
+		assert.Nil(tr.MatchInto("/A/XYZ:DEL", matched))
+		EqualPtr(t, node3, matched.Node)
+		assert.Equal(Params{{"b", "XYZ"}}, matched.Values)
+		assert.Nil(tr.MatchInto("/A/XY1:DEL", matched))
+		assert.Nil(matched.Node)
+
+		assert.Nil(tr.MatchInto("/api/user/123/", matched))
+		assert.Nil(matched.Node)
+		assert.Equal("/api/user/123", matched.TSR)
+		assert.Nil(tr.MatchInto("/api/user/123", matched))
+		assert.Equal("", matched.TSR)
+		assert.True(errors.Is(tr.MatchInto("api", matched), ErrInvalidPath))
+
+		assert.Equal("xyz:del", string(appendLower(nil, "XYZ:DEL")))
+		assert.Equal(strings.ToLower("ÄB/汉\xff"), string(appendLower(nil, "ÄB/汉\xff")))
+		assert.True(isLower("/abc/汉"[:4]))
+		assert.False(isLower("/aBc"))
+		assert.False(isLower("/汉"))
+	})
 }
 
 func TestGearTrieNode(t *testing.T) {
@@ -762,8 +1027,182 @@ func TestGearTrieNode(t *testing.T) {
 		EqualPtr(t, handler, tr.Match("/api").Node.GetHandler("GET").(func()))
 		assert.Equal("GET", tr.Match("/api").Node.GetAllow())
 
+		err := tr.Define("/").HandleE("GET", handler)
+		assert.True(errors.Is(err, ErrDuplicateHandler))
+		assert.Nil(tr.Define("/").HandleE("DELETE", handler))
+		assert.Equal("GET, PUT, DELETE", tr.Match("/").Node.GetAllow())
+
 		for _, node := range tr.GetEndpoints() {
 			fmt.Println(node.GetMethods(), node.GetPattern())
 		}
 	})
+	t.Run("Trie Lookup", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node1 := tr.Define(`/api/:resource/:ID(^\d+$)+:cancel`)
+		node2 := tr.Define("/a/::b/c")
+		tr.Define("/files/:filepath*")
+
+		EqualPtr(t, node1, tr.Lookup(`/api/:resource/:ID(^\d+$)+:cancel`))
+		EqualPtr(t, node2, tr.Lookup("/A/::b/c"))
+		assert.Nil(tr.Lookup("/api/:resource"))
+		assert.Nil(tr.Lookup("/api/:type/:ID(^\\d+$)+:cancel"))
+		assert.Nil(tr.Lookup("/files/:path*"))
+		assert.Nil(tr.Lookup("/files/:filepath*/x"))
+		assert.Nil(tr.Lookup("/x"))
+		assert.Nil(tr.Lookup("/a//b"))
+		assert.Equal(3, len(tr.GetEndpoints()))
+
+		// an empty pattern is not the root pattern
+		EqualPtr(t, tr.Define("/"), tr.Lookup("/"))
+		assert.Nil(tr.Lookup(""))
+	})
+
+	t.Run("Node URL", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node := tr.Define(`/api/:resource/:ID(^\d+$)+:cancel`)
+		url, err := node.URL(map[string]string{"resource": "task", "ID": "123"})
+		assert.Nil(err)
+		assert.Equal("/api/task/123:cancel", url)
+		res := tr.Match(url)
+		EqualPtr(t, node, res.Node)
+		assert.Equal("123", res.Params["ID"])
+
+		_, err = node.URL(map[string]string{"resource": "task", "ID": "abc"})
+		assert.True(errors.Is(err, ErrInvalidParam))
+		_, err = node.URL(map[string]string{"ID": "123"})
+		assert.True(errors.Is(err, ErrInvalidParam))
+		assert.Equal(`param "resource" is required in "/api/:resource"`, err.Error())
+		_, err = node.URL(map[string]string{"resource": "a/b", "ID": "123"})
+		assert.True(errors.Is(err, ErrInvalidParam))
+
+		node = tr.Define("/files/:filepath*")
+		url, err = node.URL(map[string]string{"filepath": "a b/c?.html"})
+		assert.Nil(err)
+		assert.Equal("/files/a%20b/c%3F.html", url)
+
+		url, err = tr.Define("/a/::b/Cd/").URL(nil)
+		assert.Nil(err)
+		assert.Equal("/a/:b/Cd/", url)
+
+		url, err = tr.Define("/").URL(nil)
+		assert.Nil(err)
+		assert.Equal("/", url)
+
+		url, err = tr.Define("/user/:name").URL(map[string]string{"name": "汉 字"})
+		assert.Nil(err)
+		assert.Equal("/user/%E6%B1%89%20%E5%AD%97", url)
+	})
+	t.Run("Trie Remove", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func() {}
+		tr := New()
+		tr.Define("/a").Handle("GET", handler)
+		tr.Define("/a/b/c").Handle("GET", handler)
+		tr.Define("/a/:id/d").Handle("GET", handler)
+		tr.Define("/a/:w*").Handle("GET", handler)
+		tr.Define("/x/y/").Handle("GET", handler)
+
+		assert.False(tr.Remove("/a/b"))
+		assert.True(tr.Remove("/a/b/c"))
+		assert.False(tr.Remove("/a/b/c"))
+		assert.Nil(tr.Match("/a/b/c").Node)
+		assert.Nil(tr.Define("/a").getChild("b"))
+
+		assert.True(tr.Remove("/a/:id/d"))
+		assert.Equal(1, len(tr.Define("/a").varyChildren))
+		EqualPtr(t, tr.Lookup("/a/:w*"), tr.Match("/a/b/c").Node)
+
+		assert.True(tr.Remove("/a"))
+		assert.Nil(tr.Match("/a").Node)
+		assert.NotNil(tr.Match("/a/b").Node)
+		assert.True(tr.Remove("/a/:w*"))
+		assert.Nil(tr.root.getChild("a"))
+
+		assert.True(tr.Remove("/x/y/"))
+		assert.Equal(0, len(tr.root.children))
+		assert.Equal(0, len(tr.GetEndpoints()))
+
+		node := tr.Define("/a/b/c")
+		assert.Equal("/a/b/c", node.GetPattern())
+		tr.Define("/a/:x/c")
+		EqualPtr(t, node, tr.Match("/a/b/c").Node)
+	})
+
+	t.Run("Node Unhandle and Replace", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler1 := func() {}
+		handler2 := func() {}
+		tr := New()
+		node := tr.Define("/a/:b")
+		node.Handle("GET", handler1)
+		node.Handle("PUT", handler1)
+		node.Handle("POST", handler1)
+
+		EqualPtr(t, handler1, node.Replace("PUT", handler2))
+		EqualPtr(t, handler2, node.GetHandler("PUT"))
+		assert.Nil(node.Replace("DELETE", handler2))
+		assert.Equal("GET, PUT, POST, DELETE", node.GetAllow())
+
+		assert.False(node.Unhandle("PATCH"))
+		assert.True(node.Unhandle("PUT"))
+		assert.Nil(node.GetHandler("PUT"))
+		assert.Equal("GET, POST, DELETE", node.GetAllow())
+		assert.True(node.Unhandle("GET"))
+		assert.True(node.Unhandle("DELETE"))
+		assert.Equal("POST", node.GetAllow())
+		EqualPtr(t, node, tr.Match("/a/x").Node)
+
+		assert.True(node.Unhandle("POST"))
+		assert.Equal("", node.GetAllow())
+		assert.Nil(tr.Match("/a/x").Node)
+		assert.Nil(tr.Lookup("/a/:b"))
+		assert.Equal(0, len(tr.root.children))
+	})
+	t.Run("Node SetMeta and GetMeta", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tr := New()
+		node := tr.Define("/a/:b")
+		assert.False(node.SetMeta("GET", "get b"))
+		assert.Nil(node.GetMeta("GET"))
+
+		node.Handle("GET", func() {})
+		node.Handle("PUT", func() {})
+		assert.True(node.SetMeta("GET", "get b"))
+		assert.Equal("get b", node.GetMeta("GET"))
+		assert.Nil(node.GetMeta("PUT"))
+		assert.Equal("get b", tr.Clone().Lookup("/a/:b").GetMeta("GET"))
+
+		assert.True(node.Unhandle("GET"))
+		assert.Nil(node.GetMeta("GET"))
+	})
+	t.Run("Trie Clone", func(t *testing.T) {
+		assert := assert.New(t)
+
+		handler := func() {}
+		tr := New(Options{IgnoreCase: false})
+		tr.Define("/a/:b(^\\d+$)/c").Handle("GET", handler)
+		tr.Define("/a/:w*").Handle("GET", handler)
+
+		c := tr.Clone()
+		assert.False(c.ignoreCase)
+		node := c.Lookup("/a/:b(^\\d+$)/c")
+		NotEqualPtr(t, tr.Lookup("/a/:b(^\\d+$)/c"), node)
+		EqualPtr(t, node, c.Match("/a/1/c").Node)
+		EqualPtr(t, c.Lookup("/a/:w*"), c.Match("/a/x/c").Node)
+		EqualPtr(t, handler, node.GetHandler("GET"))
+		EqualPtr(t, node.parent.parent, c.Lookup("/a/:w*").parent)
+
+		c.Define("/x").Handle("GET", handler)
+		assert.True(c.Remove("/a/:w*"))
+		assert.Nil(tr.Match("/x").Node)
+		assert.NotNil(tr.Match("/a/x/c").Node)
+		assert.Nil(c.Match("/a/x/c").Node)
+	})
 }